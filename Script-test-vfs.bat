@@ -7,7 +7,7 @@ echo ls >> test_script.txt
 echo vfs-save saved_vfs >> test_script.txt
 
 REM Запускаем shell с скриптом
-go build -o MIREA-Configuration-management-3.exe main.go
+go build -o MIREA-Configuration-management-3.exe .
 MIREA-Configuration-management-3.exe -script test_script.txt
 
 echo.
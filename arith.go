@@ -0,0 +1,406 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Узел выражения $((...))
+type arithNode struct {
+	op          string     // оператор; "num" - число, "var" - переменная
+	value       int64      // значение числа
+	name        string     // имя переменной
+	left, right *arithNode // операнды
+	cond        *arithNode // условие тернарного оператора
+}
+
+// Приоритеты бинарных операторов, чем больше - тем сильнее связывание
+var arithPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"|":  3,
+	"^":  4,
+	"&":  5,
+	"==": 6, "!=": 6,
+	"<": 7, "<=": 7, ">": 7, ">=": 7,
+	"<<": 8, ">>": 8,
+	"+": 9, "-": 9,
+	"*": 10, "/": 10, "%": 10,
+	"**": 11,
+}
+
+// Операторы выражений, более длинные идут раньше
+var arithOperators = []string{
+	"<<=", ">>=", "**", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||", "++", "--",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=",
+	"+", "-", "*", "/", "%", "<", ">", "&", "|", "^", "!", "~", "?", ":", "=", "(", ")",
+}
+
+// Разбор и вычисление целочисленных выражений
+type arithParser struct {
+	tokens []string
+	pos    int
+}
+
+// Вычисляет целочисленное выражение. Переменные читаются и изменяются в оболочке
+func (s *Shell) evalArith(expr string) (int64, error) {
+	tokens, err := lexArith(expr)
+	if err != nil {
+		return 0, err
+	}
+	if len(tokens) == 0 {
+		return 0, nil
+	}
+	p := &arithParser{tokens: tokens}
+	node, err := p.parseAssign()
+	if err != nil {
+		return 0, err
+	}
+	if p.pos < len(p.tokens) {
+		return 0, fmt.Errorf("%s: syntax error in expression (error token is \"%s\")", strings.TrimSpace(expr), p.tokens[p.pos])
+	}
+	return s.evalArithNode(node)
+}
+
+func lexArith(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(expr) && (isAlnum(expr[j])) {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		case c == '_' || isAlpha(c):
+			j := i
+			for j < len(expr) && (expr[j] == '_' || isAlnum(expr[j])) {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		default:
+			found := false
+			for _, op := range arithOperators {
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, op)
+					i += len(op)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("%s: syntax error: invalid arithmetic operator (error token is \"%s\")", strings.TrimSpace(expr), expr[i:])
+			}
+		}
+	}
+	return tokens, nil
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isAlnum(c byte) bool {
+	return isAlpha(c) || (c >= '0' && c <= '9')
+}
+
+func (p *arithParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *arithParser) errorf() error {
+	if p.pos >= len(p.tokens) {
+		return fmt.Errorf("syntax error: operand expected")
+	}
+	return fmt.Errorf("syntax error in expression (error token is \"%s\")", p.tokens[p.pos])
+}
+
+// assign := ternary | NAME (= | += | ...) assign
+func (p *arithParser) parseAssign() (*arithNode, error) {
+	if p.pos+1 < len(p.tokens) && isValidName(p.tokens[p.pos]) {
+		op := p.tokens[p.pos+1]
+		if op == "=" || (len(op) >= 2 && strings.HasSuffix(op, "=") && !arithComparison(op)) {
+			name := p.tokens[p.pos]
+			p.pos += 2
+			value, err := p.parseAssign()
+			if err != nil {
+				return nil, err
+			}
+			return &arithNode{op: op, name: name, right: value}, nil
+		}
+	}
+	return p.parseTernary()
+}
+
+func arithComparison(op string) bool {
+	return op == "==" || op == "!=" || op == "<=" || op == ">="
+}
+
+// ternary := binary ['?' assign ':' ternary]
+func (p *arithParser) parseTernary() (*arithNode, error) {
+	cond, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != "?" {
+		return cond, nil
+	}
+	p.pos++
+	left, err := p.parseAssign()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ":" {
+		return nil, p.errorf()
+	}
+	p.pos++
+	right, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return &arithNode{op: "?", cond: cond, left: left, right: right}, nil
+}
+
+// Разбор бинарных операторов методом подъема по приоритетам
+func (p *arithParser) parseBinary(minPrec int) (*arithNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		prec, ok := arithPrecedence[op]
+		if !ok || prec < minPrec {
+			return left, nil
+		}
+		p.pos++
+		// ** правоассоциативен
+		nextPrec := prec + 1
+		if op == "**" {
+			nextPrec = prec
+		}
+		right, err := p.parseBinary(nextPrec)
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: op, left: left, right: right}
+	}
+}
+
+// unary := ('-' | '+' | '!' | '~' | '++' | '--') unary | postfix
+func (p *arithParser) parseUnary() (*arithNode, error) {
+	switch op := p.peek(); op {
+	case "-", "+", "!", "~":
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &arithNode{op: "u" + op, left: operand}, nil
+	case "++", "--":
+		p.pos++
+		name := p.peek()
+		if !isValidName(name) {
+			return nil, p.errorf()
+		}
+		p.pos++
+		return &arithNode{op: "pre" + op, name: name}, nil
+	}
+	return p.parsePostfix()
+}
+
+// postfix := primary | NAME ('++' | '--')
+func (p *arithParser) parsePostfix() (*arithNode, error) {
+	tok := p.peek()
+	switch {
+	case tok == "(":
+		p.pos++
+		node, err := p.parseAssign()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, p.errorf()
+		}
+		p.pos++
+		return node, nil
+	case tok != "" && tok[0] >= '0' && tok[0] <= '9':
+		p.pos++
+		value, err := strconv.ParseInt(tok, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: value too great for base (error token is \"%s\")", tok, tok)
+		}
+		return &arithNode{op: "num", value: value}, nil
+	case isValidName(tok):
+		p.pos++
+		if next := p.peek(); next == "++" || next == "--" {
+			p.pos++
+			return &arithNode{op: "post" + next, name: tok}, nil
+		}
+		return &arithNode{op: "var", name: tok}, nil
+	}
+	return nil, p.errorf()
+}
+
+// Значение переменной в выражении: пустая строка - 0, иначе значение вычисляется как выражение
+func (s *Shell) arithVar(name string) (int64, error) {
+	value := strings.TrimSpace(s.lookupVar(name))
+	if value == "" {
+		return 0, nil
+	}
+	if n, err := strconv.ParseInt(value, 0, 64); err == nil {
+		return n, nil
+	}
+	return s.evalArith(value)
+}
+
+func (s *Shell) evalArithNode(n *arithNode) (int64, error) {
+	switch n.op {
+	case "num":
+		return n.value, nil
+	case "var":
+		return s.arithVar(n.name)
+	case "?":
+		cond, err := s.evalArithNode(n.cond)
+		if err != nil {
+			return 0, err
+		}
+		if cond != 0 {
+			return s.evalArithNode(n.left)
+		}
+		return s.evalArithNode(n.right)
+	case "&&", "||":
+		// логические операторы вычисляются по короткой схеме
+		left, err := s.evalArithNode(n.left)
+		if err != nil {
+			return 0, err
+		}
+		if (n.op == "&&") != (left != 0) {
+			return boolToInt(left != 0), nil
+		}
+		right, err := s.evalArithNode(n.right)
+		return boolToInt(right != 0), err
+	case "u-", "u+", "u!", "u~":
+		value, err := s.evalArithNode(n.left)
+		if err != nil {
+			return 0, err
+		}
+		switch n.op {
+		case "u-":
+			return -value, nil
+		case "u!":
+			return boolToInt(value == 0), nil
+		case "u~":
+			return ^value, nil
+		}
+		return value, nil
+	case "pre++", "pre--", "post++", "post--":
+		value, err := s.arithVar(n.name)
+		if err != nil {
+			return 0, err
+		}
+		next := value + 1
+		if strings.HasSuffix(n.op, "--") {
+			next = value - 1
+		}
+		s.vars[n.name] = strconv.FormatInt(next, 10)
+		if strings.HasPrefix(n.op, "pre") {
+			return next, nil
+		}
+		return value, nil
+	}
+	if strings.HasSuffix(n.op, "=") && !arithComparison(n.op) {
+		// присваивание: NAME = expr, NAME += expr, ...
+		value, err := s.evalArithNode(n.right)
+		if err != nil {
+			return 0, err
+		}
+		if n.op != "=" {
+			current, err := s.arithVar(n.name)
+			if err != nil {
+				return 0, err
+			}
+			if value, err = arithBinary(strings.TrimSuffix(n.op, "="), current, value); err != nil {
+				return 0, err
+			}
+		}
+		s.vars[n.name] = strconv.FormatInt(value, 10)
+		return value, nil
+	}
+	left, err := s.evalArithNode(n.left)
+	if err != nil {
+		return 0, err
+	}
+	right, err := s.evalArithNode(n.right)
+	if err != nil {
+		return 0, err
+	}
+	return arithBinary(n.op, left, right)
+}
+
+func arithBinary(op string, left, right int64) (int64, error) {
+	switch op {
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/", "%":
+		if right == 0 {
+			return 0, fmt.Errorf("division by 0")
+		}
+		if op == "/" {
+			return left / right, nil
+		}
+		return left % right, nil
+	case "**":
+		if right < 0 {
+			return 0, fmt.Errorf("exponent less than 0")
+		}
+		result := int64(1)
+		for ; right > 0; right-- {
+			result *= left
+		}
+		return result, nil
+	case "<<":
+		return left << uint64(right), nil
+	case ">>":
+		return left >> uint64(right), nil
+	case "&":
+		return left & right, nil
+	case "|":
+		return left | right, nil
+	case "^":
+		return left ^ right, nil
+	case "==":
+		return boolToInt(left == right), nil
+	case "!=":
+		return boolToInt(left != right), nil
+	case "<":
+		return boolToInt(left < right), nil
+	case "<=":
+		return boolToInt(left <= right), nil
+	case ">":
+		return boolToInt(left > right), nil
+	case ">=":
+		return boolToInt(left >= right), nil
+	}
+	return 0, fmt.Errorf("unknown operator %s", op)
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,927 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Лексема awk: 'n' - число, 's' - строка, 'r' - регулярное выражение,
+// 'w' - имя или ключевое слово, 'o' - оператор, '\n' - перевод строки
+type awkToken struct {
+	kind byte
+	text string
+}
+
+// Операторы awk, более длинные идут раньше
+var awkOperators = []string{
+	"&&", "||", "==", "!=", "<=", ">=", "!~",
+	"+", "-", "*", "/", "%", "!", "<", ">", "~", "(", ")", "{", "}", ";", ",", "$",
+}
+
+var awkKeywords = map[string]bool{"BEGIN": true, "END": true, "print": true, "printf": true}
+
+// Может ли после лексемы стоять деление; иначе "/" начинает регулярное выражение
+func awkOperand(tok awkToken) bool {
+	switch tok.kind {
+	case 'n', 's', 'r':
+		return true
+	case 'w':
+		return !awkKeywords[tok.text]
+	case 'o':
+		return tok.text == ")"
+	}
+	return false
+}
+
+func lexAwk(src string) ([]awkToken, error) {
+	var tokens []awkToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			i += 2 // продолжение строки
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '\n':
+			tokens = append(tokens, awkToken{kind: '\n'})
+			i++
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			if j < len(src) && (src[j] == 'e' || src[j] == 'E') {
+				k := j + 1
+				if k < len(src) && (src[k] == '+' || src[k] == '-') {
+					k++
+				}
+				if k < len(src) && src[k] >= '0' && src[k] <= '9' {
+					for j = k; j < len(src) && src[j] >= '0' && src[j] <= '9'; j++ {
+					}
+				}
+			}
+			tokens = append(tokens, awkToken{kind: 'n', text: src[i:j]})
+			i = j
+		case c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != '"'; j++ {
+				if src[j] == '\n' {
+					return nil, fmt.Errorf("newline in string")
+				}
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+					switch src[j] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					case 'r':
+						b.WriteByte('\r')
+					default:
+						b.WriteByte(src[j])
+					}
+					continue
+				}
+				b.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("non-terminated string")
+			}
+			tokens = append(tokens, awkToken{kind: 's', text: b.String()})
+			i = j + 1
+		case c == '/' && (len(tokens) == 0 || !awkOperand(tokens[len(tokens)-1])):
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != '/'; j++ {
+				if src[j] == '\n' {
+					return nil, fmt.Errorf("newline in regex")
+				}
+				if src[j] == '\\' && j+1 < len(src) && src[j+1] == '/' {
+					j++
+				} else if src[j] == '\\' && j+1 < len(src) {
+					b.WriteByte(src[j])
+					j++
+				}
+				b.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("non-terminated regular expression")
+			}
+			tokens = append(tokens, awkToken{kind: 'r', text: b.String()})
+			i = j + 1
+		case c == '_' || isAlpha(c):
+			j := i
+			for j < len(src) && (src[j] == '_' || isAlnum(src[j])) {
+				j++
+			}
+			tokens = append(tokens, awkToken{kind: 'w', text: src[i:j]})
+			i = j
+		default:
+			found := false
+			for _, op := range awkOperators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, awkToken{kind: 'o', text: op})
+					i += len(op)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("syntax error at '%c'", c)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// Узел выражения awk
+type awkNode struct {
+	op          string // оператор; "num", "str", "regex", "var", "$", "concat"
+	num         float64
+	str         string // строка или имя переменной
+	re          *regexp.Regexp
+	left, right *awkNode
+}
+
+// Оператор действия: print или printf с аргументами
+type awkStmt struct {
+	kind string
+	args []*awkNode
+}
+
+// Правило программы: шаблон BEGIN, END, выражение или отсутствует
+type awkRule struct {
+	begin, end bool
+	pattern    *awkNode
+	action     []*awkStmt
+	hasAction  bool
+}
+
+type awkParser struct {
+	tokens []awkToken
+	pos    int
+	noGT   bool // внутри print: ">" - перенаправление вывода, а не сравнение
+}
+
+func (p *awkParser) peek() awkToken {
+	if p.pos >= len(p.tokens) {
+		return awkToken{}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *awkParser) is(text string) bool {
+	tok := p.peek()
+	return (tok.kind == 'o' || tok.kind == 'w') && tok.text == text
+}
+
+func (p *awkParser) errorf() error {
+	tok := p.peek()
+	switch tok.kind {
+	case 0:
+		return fmt.Errorf("syntax error: unexpected end of program")
+	case '\n':
+		return fmt.Errorf("syntax error: unexpected newline")
+	}
+	return fmt.Errorf("syntax error near '%s'", tok.text)
+}
+
+func (p *awkParser) expect(text string) error {
+	if !p.is(text) {
+		return p.errorf()
+	}
+	p.pos++
+	return nil
+}
+
+func (p *awkParser) skipNewlines() {
+	for p.peek().kind == '\n' {
+		p.pos++
+	}
+}
+
+// Пропускает разделители операторов: переводы строк и ";"
+func (p *awkParser) skipTerminators() {
+	for p.peek().kind == '\n' || p.is(";") {
+		p.pos++
+	}
+}
+
+// program := (rule terminators)*
+func (p *awkParser) parseProgram() ([]*awkRule, error) {
+	var rules []*awkRule
+	for {
+		p.skipTerminators()
+		if p.pos >= len(p.tokens) {
+			return rules, nil
+		}
+		rule := &awkRule{}
+		switch {
+		case p.is("BEGIN"):
+			rule.begin = true
+			p.pos++
+		case p.is("END"):
+			rule.end = true
+			p.pos++
+		case !p.is("{"):
+			pattern, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			rule.pattern = pattern
+		}
+		if p.is("{") {
+			body, err := p.parseBlock()
+			if err != nil {
+				return nil, err
+			}
+			rule.action, rule.hasAction = body, true
+		} else if rule.begin || rule.end {
+			return nil, p.errorf()
+		}
+		// Шаблон без действия завершается переводом строки или ";"
+		if tok := p.peek(); !rule.hasAction && tok.kind != 0 && tok.kind != '\n' && !p.is(";") {
+			return nil, p.errorf()
+		}
+		rules = append(rules, rule)
+	}
+}
+
+// block := '{' (statement terminators)* '}'
+func (p *awkParser) parseBlock() ([]*awkStmt, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var stmts []*awkStmt
+	for {
+		p.skipTerminators()
+		if p.is("}") {
+			p.pos++
+			return stmts, nil
+		}
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+		if tok := p.peek(); tok.kind != '\n' && !p.is(";") && !p.is("}") {
+			return nil, p.errorf()
+		}
+	}
+}
+
+// statement := ('print' | 'printf') [expr (',' expr)*]
+func (p *awkParser) parseStatement() (*awkStmt, error) {
+	tok := p.peek()
+	if !p.is("print") && !p.is("printf") {
+		return nil, p.errorf()
+	}
+	p.pos++
+	stmt := &awkStmt{kind: tok.text}
+	// print (a, b) - скобки вокруг всего списка аргументов
+	parens := p.is("(")
+	if parens {
+		depth, end := 0, p.pos
+		for ; end < len(p.tokens); end++ {
+			if t := p.tokens[end]; t.kind == 'o' && t.text == "(" {
+				depth++
+			} else if t.kind == 'o' && t.text == ")" {
+				if depth--; depth == 0 {
+					break
+				}
+			}
+		}
+		next := awkToken{}
+		if end+1 < len(p.tokens) {
+			next = p.tokens[end+1]
+		}
+		parens = next.kind == 0 || next.kind == '\n' || next.kind == 'o' && (next.text == ";" || next.text == "}" || next.text == ">")
+	}
+	if parens {
+		p.pos++
+	}
+	p.noGT = !parens
+	for !p.endOfPrint(parens) {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		stmt.args = append(stmt.args, arg)
+		if !p.is(",") {
+			break
+		}
+		p.pos++
+		p.skipNewlines()
+	}
+	p.noGT = false
+	if parens {
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	if p.is(">") {
+		return nil, fmt.Errorf("output redirection is not supported")
+	}
+	if stmt.kind == "printf" && len(stmt.args) == 0 {
+		return nil, fmt.Errorf("printf: no format")
+	}
+	return stmt, nil
+}
+
+// Заканчивается ли список аргументов print
+func (p *awkParser) endOfPrint(parens bool) bool {
+	tok := p.peek()
+	return tok.kind == 0 || tok.kind == '\n' || p.is(";") || p.is("}") || p.is(">") || parens && p.is(")")
+}
+
+// Уровни бинарных операторов от слабого к сильному; "concat" - конкатенация подряд идущих выражений
+var awkLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"~", "!~"},
+	{"<", "<=", "!=", "==", ">", ">="},
+	{"concat"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *awkParser) parseExpr() (*awkNode, error) {
+	return p.parseBinary(0)
+}
+
+// Может ли лексема начинать выражение, присоединяемое конкатенацией
+func (p *awkParser) startsConcat() bool {
+	tok := p.peek()
+	switch tok.kind {
+	case 'n', 's':
+		return true
+	case 'w':
+		return !awkKeywords[tok.text]
+	case 'o':
+		return tok.text == "$" || tok.text == "("
+	}
+	return false
+}
+
+func (p *awkParser) parseBinary(level int) (*awkNode, error) {
+	if level == len(awkLevels) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		if awkLevels[level][0] == "concat" {
+			if !p.startsConcat() {
+				return left, nil
+			}
+			op = "concat"
+		} else {
+			tok := p.peek()
+			if tok.kind != 'o' {
+				return left, nil
+			}
+			for _, candidate := range awkLevels[level] {
+				if tok.text == candidate && !(candidate == ">" && p.noGT) {
+					op = candidate
+				}
+			}
+			if op == "" {
+				return left, nil
+			}
+			p.pos++
+			if op == "&&" || op == "||" {
+				p.skipNewlines()
+			}
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &awkNode{op: op, left: left, right: right}
+	}
+}
+
+// unary := ('!' | '-' | '+') unary | primary
+func (p *awkParser) parseUnary() (*awkNode, error) {
+	if tok := p.peek(); tok.kind == 'o' && (tok.text == "!" || tok.text == "-" || tok.text == "+") {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &awkNode{op: "u" + tok.text, left: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+// primary := NUMBER | STRING | /REGEX/ | '$' primary | '(' expr ')' | NAME
+func (p *awkParser) parsePrimary() (*awkNode, error) {
+	tok := p.peek()
+	switch {
+	case tok.kind == 'n':
+		p.pos++
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", tok.text)
+		}
+		return &awkNode{op: "num", num: n}, nil
+	case tok.kind == 's':
+		p.pos++
+		return &awkNode{op: "str", str: tok.text}, nil
+	case tok.kind == 'r':
+		p.pos++
+		re, err := regexp.Compile(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression /%s/", tok.text)
+		}
+		return &awkNode{op: "regex", re: re}, nil
+	case p.is("$"):
+		p.pos++
+		index, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &awkNode{op: "$", left: index}, nil
+	case p.is("("):
+		p.pos++
+		saved := p.noGT
+		p.noGT = false
+		node, err := p.parseExpr()
+		p.noGT = saved
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	case tok.kind == 'w' && !awkKeywords[tok.text]:
+		p.pos++
+		if p.is("(") {
+			return nil, fmt.Errorf("calling undefined function %s", tok.text)
+		}
+		return &awkNode{op: "var", str: tok.text}, nil
+	}
+	return nil, p.errorf()
+}
+
+// Значение awk: число, строка или строка из входных данных, похожая на число (strnum)
+type awkValue struct {
+	kind byte // 'n' - число, 's' - строка, 'm' - strnum и неинициализированное значение
+	num  float64
+	str  string
+}
+
+func awkNum(n float64) awkValue   { return awkValue{kind: 'n', num: n} }
+func awkString(s string) awkValue { return awkValue{kind: 's', str: s} }
+func awkBool(b bool) awkValue     { return awkNum(float64(boolToInt(b))) }
+
+// Число в выводе: целые - без дробной части, остальные - по формату %.6g
+func awkFormatNumber(n float64) string {
+	if n == math.Trunc(n) && math.Abs(n) < 1e16 {
+		return strconv.FormatInt(int64(n), 10)
+	}
+	return strconv.FormatFloat(n, 'g', 6, 64)
+}
+
+// Строка из входных данных: числовая, если целиком похожа на число
+func awkInput(s string) awkValue {
+	if n, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil && !strings.ContainsAny(s, "xXnN") {
+		return awkValue{kind: 'm', num: n, str: s}
+	}
+	return awkString(s)
+}
+
+func (v awkValue) toNum() float64 {
+	if v.kind == 's' {
+		return leadingNumber(v.str)
+	}
+	return v.num
+}
+
+func (v awkValue) String() string {
+	if v.kind == 'n' {
+		return awkFormatNumber(v.num)
+	}
+	return v.str
+}
+
+func (v awkValue) toBool() bool {
+	if v.kind == 's' {
+		return v.str != ""
+	}
+	return v.num != 0
+}
+
+// Состояние выполнения программы awk
+type awkInterp struct {
+	vars    map[string]awkValue
+	fields  []string // fields[0] - вся запись $0
+	regexps map[string]*regexp.Regexp
+	out     io.Writer
+}
+
+func newAwkInterp(out io.Writer) *awkInterp {
+	return &awkInterp{
+		vars: map[string]awkValue{
+			"FS": awkString(" "), "OFS": awkString(" "), "ORS": awkString("\n"),
+			"NR": awkNum(0), "FNR": awkNum(0), "FILENAME": awkString(""),
+		},
+		fields:  []string{""},
+		regexps: map[string]*regexp.Regexp{},
+		out:     out,
+	}
+}
+
+// Регулярное выражение из строки; скомпилированные выражения кэшируются
+func (a *awkInterp) regexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := a.regexps[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression '%s'", pattern)
+	}
+	a.regexps[pattern] = re
+	return re, nil
+}
+
+// Устанавливает запись $0 и разбивает ее на поля по FS:
+// " " - по группам пробелов, один символ - по нему, иначе - по регулярному выражению
+func (a *awkInterp) setRecord(record string) error {
+	fs := a.vars["FS"].String()
+	var fields []string
+	switch {
+	case fs == " ":
+		fields = strings.Fields(record)
+	case record == "":
+	case len([]rune(fs)) == 1 && fs != "\\":
+		fields = strings.Split(record, fs)
+	default:
+		re, err := a.regexp(fs)
+		if err != nil {
+			return err
+		}
+		fields = re.Split(record, -1)
+	}
+	a.fields = append([]string{record}, fields...)
+	return nil
+}
+
+func (a *awkInterp) getVar(name string) awkValue {
+	if name == "NF" {
+		return awkNum(float64(len(a.fields) - 1))
+	}
+	if v, ok := a.vars[name]; ok {
+		return v
+	}
+	return awkValue{kind: 'm'}
+}
+
+func (a *awkInterp) field(i int) (awkValue, error) {
+	if i < 0 {
+		return awkValue{}, fmt.Errorf("trying to access out of range field %d", i)
+	}
+	if i >= len(a.fields) {
+		return awkValue{kind: 'm'}, nil
+	}
+	return awkInput(a.fields[i]), nil
+}
+
+// Сравнивает значения: числа и strnum - как числа, остальное - как строки
+func awkCompare(left, right awkValue) int {
+	if left.kind != 's' && right.kind != 's' {
+		switch {
+		case left.num < right.num:
+			return -1
+		case left.num > right.num:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(left.String(), right.String())
+}
+
+// Регулярное выражение правого операнда ~: литерал /.../ или строка
+func (a *awkInterp) matcher(n *awkNode) (*regexp.Regexp, error) {
+	if n.op == "regex" {
+		return n.re, nil
+	}
+	v, err := a.eval(n)
+	if err != nil {
+		return nil, err
+	}
+	return a.regexp(v.String())
+}
+
+func (a *awkInterp) eval(n *awkNode) (awkValue, error) {
+	switch n.op {
+	case "num":
+		return awkNum(n.num), nil
+	case "str":
+		return awkString(n.str), nil
+	case "regex":
+		// /RE/ в выражении - сопоставление с $0
+		return awkBool(n.re.MatchString(a.fields[0])), nil
+	case "var":
+		return a.getVar(n.str), nil
+	case "$":
+		index, err := a.eval(n.left)
+		if err != nil {
+			return awkValue{}, err
+		}
+		return a.field(int(index.toNum()))
+	case "&&", "||":
+		// логические операторы вычисляются по короткой схеме
+		left, err := a.eval(n.left)
+		if err != nil {
+			return awkValue{}, err
+		}
+		if (n.op == "&&") != left.toBool() {
+			return awkBool(left.toBool()), nil
+		}
+		right, err := a.eval(n.right)
+		return awkBool(right.toBool()), err
+	case "~", "!~":
+		left, err := a.eval(n.left)
+		if err != nil {
+			return awkValue{}, err
+		}
+		re, err := a.matcher(n.right)
+		if err != nil {
+			return awkValue{}, err
+		}
+		return awkBool(re.MatchString(left.String()) == (n.op == "~")), nil
+	case "u!", "u-", "u+":
+		v, err := a.eval(n.left)
+		switch n.op {
+		case "u!":
+			return awkBool(!v.toBool()), err
+		case "u-":
+			return awkNum(-v.toNum()), err
+		}
+		return awkNum(v.toNum()), err
+	}
+	left, err := a.eval(n.left)
+	if err != nil {
+		return awkValue{}, err
+	}
+	right, err := a.eval(n.right)
+	if err != nil {
+		return awkValue{}, err
+	}
+	switch n.op {
+	case "concat":
+		return awkString(left.String() + right.String()), nil
+	case "<":
+		return awkBool(awkCompare(left, right) < 0), nil
+	case "<=":
+		return awkBool(awkCompare(left, right) <= 0), nil
+	case ">":
+		return awkBool(awkCompare(left, right) > 0), nil
+	case ">=":
+		return awkBool(awkCompare(left, right) >= 0), nil
+	case "==":
+		return awkBool(awkCompare(left, right) == 0), nil
+	case "!=":
+		return awkBool(awkCompare(left, right) != 0), nil
+	}
+	x, y := left.toNum(), right.toNum()
+	switch n.op {
+	case "+":
+		return awkNum(x + y), nil
+	case "-":
+		return awkNum(x - y), nil
+	case "*":
+		return awkNum(x * y), nil
+	}
+	if y == 0 {
+		return awkValue{}, fmt.Errorf("division by zero")
+	}
+	if n.op == "/" {
+		return awkNum(x / y), nil
+	}
+	return awkNum(math.Mod(x, y)), nil
+}
+
+// Форматирует значения по формату printf: %d, %i, %o, %x, %X, %u, %c, %s, %e, %f, %g, %%
+func awkSprintf(format string, args []awkValue) string {
+	var b strings.Builder
+	next := func() awkValue {
+		if len(args) == 0 {
+			return awkValue{kind: 'm'}
+		}
+		v := args[0]
+		args = args[1:]
+		return v
+	}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		j := i + 1
+		for j < len(format) && strings.IndexByte("-+ #0123456789.", format[j]) >= 0 {
+			j++
+		}
+		if j >= len(format) {
+			b.WriteString(format[i:])
+			break
+		}
+		spec := format[i:j]
+		switch verb := format[j]; verb {
+		case '%':
+			b.WriteByte('%')
+		case 'd', 'i':
+			fmt.Fprintf(&b, spec+"d", int64(next().toNum()))
+		case 'o', 'x', 'X':
+			fmt.Fprintf(&b, spec+string(verb), int64(next().toNum()))
+		case 'u':
+			fmt.Fprintf(&b, spec+"d", uint64(int64(next().toNum())))
+		case 'c':
+			v := next()
+			if v.kind == 'n' {
+				fmt.Fprintf(&b, spec+"c", rune(v.num))
+			} else if r := []rune(v.String()); len(r) > 0 {
+				fmt.Fprintf(&b, spec+"c", r[0])
+			}
+		case 's':
+			fmt.Fprintf(&b, spec+"s", next().String())
+		case 'e', 'E', 'f', 'F', 'g', 'G':
+			fmt.Fprintf(&b, spec+string(verb), next().toNum())
+		default:
+			b.WriteString(format[i : j+1])
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// Выполняет операторы print и printf действия
+func (a *awkInterp) exec(stmts []*awkStmt) error {
+	for _, stmt := range stmts {
+		values := make([]awkValue, len(stmt.args))
+		for i, arg := range stmt.args {
+			v, err := a.eval(arg)
+			if err != nil {
+				return err
+			}
+			values[i] = v
+		}
+		if stmt.kind == "printf" {
+			io.WriteString(a.out, awkSprintf(values[0].String(), values[1:]))
+			continue
+		}
+		if len(values) == 0 {
+			io.WriteString(a.out, a.fields[0]+a.vars["ORS"].String())
+			continue
+		}
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = v.String()
+		}
+		io.WriteString(a.out, strings.Join(parts, a.vars["OFS"].String())+a.vars["ORS"].String())
+	}
+	return nil
+}
+
+// Обрабатывает запись: выполняет действия правил, шаблон которых подходит
+func (a *awkInterp) record(rules []*awkRule, line string) error {
+	if err := a.setRecord(line); err != nil {
+		return err
+	}
+	a.vars["NR"] = awkNum(a.vars["NR"].toNum() + 1)
+	a.vars["FNR"] = awkNum(a.vars["FNR"].toNum() + 1)
+	for _, rule := range rules {
+		if rule.begin || rule.end {
+			continue
+		}
+		if rule.pattern != nil {
+			matched, err := a.eval(rule.pattern)
+			if err != nil {
+				return err
+			}
+			if !matched.toBool() {
+				continue
+			}
+		}
+		if !rule.hasAction {
+			// шаблон без действия выводит запись
+			io.WriteString(a.out, a.fields[0]+a.vars["ORS"].String())
+			continue
+		}
+		if err := a.exec(rule.action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Раскрывает экранирование в значениях -F и -v: \t, \n, \\
+func awkUnescape(s string) string {
+	return strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\\`, `\`).Replace(s)
+}
+
+func (s *Shell) awkCommand(args []string) {
+	// Выполняет программу awk для каждой строки файлов или стандартного ввода
+	opts, operands, ok := s.parseArgs("awk", args)
+	if !ok {
+		return
+	}
+	if len(operands) == 0 {
+		s.errorf("awk: no program given\n")
+		s.status = 2
+		return
+	}
+	tokens, err := lexAwk(operands[0])
+	if err != nil {
+		s.errorf("awk: %v\n", err)
+		s.status = 2
+		return
+	}
+	parser := &awkParser{tokens: tokens}
+	rules, err := parser.parseProgram()
+	if err != nil {
+		s.errorf("awk: %v\n", err)
+		s.status = 2
+		return
+	}
+	// Результат выводится по мере обработки строк, в режиме JSON - накапливается
+	out, flush := s.streamOut()
+	a := newAwkInterp(out)
+	if opts.Has("F") {
+		fs := awkUnescape(opts.Value("F"))
+		if fs == "t" {
+			fs = "\t" // -Ft - табуляция, как в POSIX awk
+		}
+		a.vars["FS"] = awkString(fs)
+	}
+	for _, assignment := range opts.Values("v") {
+		name, value, found := strings.Cut(assignment, "=")
+		if !found || !isValidName(name) {
+			s.errorf("awk: invalid -v argument '%s'\n", assignment)
+			s.status = 2
+			return
+		}
+		a.vars[name] = awkInput(awkUnescape(value))
+	}
+
+	// Выполнение: BEGIN, записи всех файлов, END
+	run := func(end bool) error {
+		for _, rule := range rules {
+			if rule.begin && !end || rule.end && end {
+				if err := a.exec(rule.action); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	err = run(false)
+	// Входные данные читаются, только если есть правила, кроме BEGIN
+	readInput := false
+	for _, rule := range rules {
+		readInput = readInput || !rule.begin
+	}
+	files := operands[1:]
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	if err == nil && readInput {
+		for _, file := range files {
+			input, ok := s.openInput("awk", file)
+			if !ok {
+				continue
+			}
+			a.vars["FILENAME"] = awkString(file)
+			a.vars["FNR"] = awkNum(0)
+			for err == nil && input.scan() {
+				err = a.record(rules, input.line)
+			}
+			input.close()
+			if err == nil && input.err != nil {
+				err = input.err
+			}
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = run(true)
+	}
+	flush()
+	if err != nil {
+		s.errorf("awk: %v\n", err)
+		s.status = 2
+	}
+}
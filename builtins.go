@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func (s *Shell) echoCommand(args []string) {
+	// Выводит аргументы через пробел
+	newline := true
+	// как и в bash, опции распознаются только в начале и не смешиваются с текстом
+	for len(args) > 0 && args[0] == "-n" {
+		newline = false
+		args = args[1:]
+	}
+	fmt.Fprint(s.out(), strings.Join(args, " "))
+	if newline {
+		fmt.Fprintln(s.out())
+	}
+}
+
+func (s *Shell) breakCommand(args []string) {
+	// Прерывает выполнение N вложенных циклов
+	s.setFlow("break", flowBreak, args)
+}
+
+func (s *Shell) continueCommand(args []string) {
+	// Переходит к следующей итерации N-го вложенного цикла
+	s.setFlow("continue", flowContinue, args)
+}
+
+func (s *Shell) setFlow(cmd string, kind int, args []string) {
+	if s.loopDepth == 0 {
+		s.errorf("%s: only meaningful in a loop\n", cmd)
+		return
+	}
+	level := 1
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			s.errorf("%s: %s: loop count out of range\n", cmd, args[0])
+			return
+		}
+		level = min(n, s.loopDepth)
+	}
+	s.flow = &flowControl{kind: kind, level: level}
+}
+
+func (s *Shell) unsetCommand(args []string) {
+	// Удаляет переменные или функции (-f)
+	opts, names, ok := s.parseArgs("unset", args)
+	if !ok {
+		return
+	}
+	for _, name := range names {
+		if opts.Has("f") {
+			delete(s.functions, name)
+			continue
+		}
+		if !isValidName(name) {
+			s.errorf("unset: '%s': not a valid identifier\n", name)
+			continue
+		}
+		delete(s.vars, name)
+	}
+}
+
+func (s *Shell) aliasCommand(args []string) {
+	// Определяет или выводит псевдонимы
+	if len(args) == 0 {
+		names := make([]string, 0, len(s.aliases))
+		for name := range s.aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(s.out(), "alias %s=%s\n", name, singleQuote(s.aliases[name]))
+		}
+		return
+	}
+	for _, arg := range args {
+		name, value, found := strings.Cut(arg, "=")
+		if !found {
+			if value, ok := s.aliases[name]; ok {
+				fmt.Fprintf(s.out(), "alias %s=%s\n", name, singleQuote(value))
+			} else {
+				s.errorf("alias: %s: not found\n", name)
+			}
+			continue
+		}
+		if name == "" || strings.ContainsAny(name, " \t\n'\"\\$;&|<>()=/") {
+			s.errorf("alias: '%s': invalid alias name\n", name)
+			continue
+		}
+		s.aliases[name] = value
+	}
+}
+
+func (s *Shell) unaliasCommand(args []string) {
+	// Удаляет псевдонимы
+	opts, names, ok := s.parseArgs("unalias", args)
+	if !ok {
+		return
+	}
+	if opts.Has("a") {
+		clear(s.aliases)
+		return
+	}
+	if len(names) == 0 {
+		s.errorf("unalias: usage: unalias [-a] NAME...\n")
+		return
+	}
+	for _, name := range names {
+		if _, ok := s.aliases[name]; !ok {
+			s.errorf("unalias: %s: not found\n", name)
+			continue
+		}
+		delete(s.aliases, name)
+	}
+}
+
+func (s *Shell) returnCommand(args []string) {
+	// Завершает функцию с указанным статусом
+	if s.callDepth == 0 {
+		s.errorf("return: can only 'return' from a function\n")
+		return
+	}
+	status := s.lastStatus
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			s.errorf("return: %s: numeric argument required\n", args[0])
+			n = 2
+		}
+		status = n
+	}
+	s.status = status
+	s.flow = &flowControl{kind: flowReturn}
+}
+
+func (s *Shell) shiftCommand(args []string) {
+	// Сдвигает позиционные параметры влево на N
+	n := 1
+	if len(args) > 0 {
+		var err error
+		n, err = strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			s.errorf("shift: %s: numeric argument required\n", args[0])
+			return
+		}
+	}
+	if n > len(s.params) {
+		s.status = 1
+		return
+	}
+	s.params = s.params[n:]
+}
+
+// Заключает строку в одинарные кавычки
+func singleQuote(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", `'\''`) + "'"
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Описание флага команды
+type FlagSpec struct {
+	Short string // короткое имя без дефиса, например "n"
+	Long  string // длинное имя без дефисов, например "lines"
+	Arg   string // имя аргумента флага, пусто если флаг без аргумента
+	Help  string // описание флага
+}
+
+// Метаданные команды: имя, строка использования, справка и флаги
+type CommandInfo struct {
+	Name  string
+	Usage string // например "tail [-n N] FILE..."
+	Short string // краткое описание в одну строку
+	Long  string // подробное описание
+	Flags []FlagSpec
+
+	// --help не перехватывается оболочкой и передается команде как обычный аргумент
+	// (test, [ и echo, для которых --help - допустимый операнд)
+	NoHelpFlag bool
+
+	// Разбор опций заканчивается на первом операнде: остальные аргументы,
+	// включая похожие на флаги, передаются скрипту (sh, source и .)
+	StopAtOperand bool
+}
+
+// Команда оболочки. Собственные команды регистрируются через Shell.Register
+type Command interface {
+	Info() CommandInfo
+	Run(args []string)
+}
+
+// Команда, обработчиком которой является обычная функция
+type FuncCommand struct {
+	CommandInfo
+	Handler func([]string)
+}
+
+func (c *FuncCommand) Info() CommandInfo {
+	return c.CommandInfo
+}
+
+func (c *FuncCommand) Run(args []string) {
+	c.Handler(args)
+}
+
+// Регистрирует команду в оболочке
+func (s *Shell) Register(cmd Command) error {
+	if cmd == nil {
+		return fmt.Errorf("register: nil command")
+	}
+	name := cmd.Info().Name
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("register: invalid command name %q", name)
+	}
+	if _, exists := s.commands[name]; exists {
+		return fmt.Errorf("register: command %s already exists", name)
+	}
+	s.commands[name] = cmd
+	s.commandOrder = append(s.commandOrder, name)
+	return nil
+}
+
+// Упрощенная регистрация встроенной команды
+func (s *Shell) registerFunc(info CommandInfo, handler func([]string)) {
+	if err := s.Register(&FuncCommand{CommandInfo: info, Handler: handler}); err != nil {
+		panic(err)
+	}
+}
+
+// Список команд в порядке регистрации
+func (s *Shell) Commands() []Command {
+	cmds := make([]Command, 0, len(s.commandOrder))
+	for _, name := range s.commandOrder {
+		cmds = append(cmds, s.commands[name])
+	}
+	return cmds
+}
+
+// Строка использования, сформированная по зарегистрированным командам
+func (s *Shell) usage() string {
+	var b strings.Builder
+	b.WriteString("Commands:\n")
+	for _, cmd := range s.Commands() {
+		info := cmd.Info()
+		usage := info.Usage
+		if usage == "" {
+			usage = info.Name
+		}
+		fmt.Fprintf(&b, "%s\n", usage)
+	}
+	return b.String()
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Унарные операторы test
+var testUnaryOps = map[string]bool{
+	"-e": true, "-f": true, "-d": true, "-s": true, "-r": true, "-w": true, "-x": true,
+	"-z": true, "-n": true,
+}
+
+// Бинарные операторы test
+var testBinaryOps = map[string]bool{
+	"=": true, "==": true, "!=": true, "<": true, ">": true,
+	"-eq": true, "-ne": true, "-lt": true, "-le": true, "-gt": true, "-ge": true,
+	"-nt": true, "-ot": true,
+}
+
+// Разбор и вычисление выражения test над узлами VFS
+type testExpr struct {
+	shell *Shell
+	args  []string
+	pos   int
+}
+
+func (s *Shell) testCommand(args []string) {
+	// Вычисляет условное выражение: 0 - истина, 1 - ложь, 2 - ошибка
+	s.evalTest("test", args)
+}
+
+func (s *Shell) bracketCommand(args []string) {
+	// [ EXPR ] - то же, что test, но с обязательной закрывающей скобкой
+	if len(args) == 0 || args[len(args)-1] != "]" {
+		s.errorf("[: missing ']'\n")
+		s.status = 2
+		return
+	}
+	s.evalTest("[", args[:len(args)-1])
+}
+
+func (s *Shell) evalTest(cmd string, args []string) {
+	if len(args) == 0 {
+		s.status = 1
+		return
+	}
+	e := &testExpr{shell: s, args: args}
+	result, err := e.parseOr()
+	if err == nil && e.pos < len(e.args) {
+		err = fmt.Errorf("unexpected argument '%s'", e.args[e.pos])
+	}
+	if err != nil {
+		s.errorf("%s: %v\n", cmd, err)
+		s.status = 2
+		return
+	}
+	if result {
+		s.status = 0
+	} else {
+		s.status = 1
+	}
+}
+
+func (e *testExpr) peek() (string, bool) {
+	if e.pos >= len(e.args) {
+		return "", false
+	}
+	return e.args[e.pos], true
+}
+
+// expr -o expr
+func (e *testExpr) parseOr() (bool, error) {
+	left, err := e.parseAnd()
+	if err != nil {
+		return false, err
+	}
+	for {
+		if arg, ok := e.peek(); !ok || arg != "-o" {
+			return left, nil
+		}
+		e.pos++
+		right, err := e.parseAnd()
+		if err != nil {
+			return false, err
+		}
+		left = left || right
+	}
+}
+
+// expr -a expr
+func (e *testExpr) parseAnd() (bool, error) {
+	left, err := e.parseNot()
+	if err != nil {
+		return false, err
+	}
+	for {
+		if arg, ok := e.peek(); !ok || arg != "-a" {
+			return left, nil
+		}
+		e.pos++
+		right, err := e.parseNot()
+		if err != nil {
+			return false, err
+		}
+		left = left && right
+	}
+}
+
+// ! expr
+func (e *testExpr) parseNot() (bool, error) {
+	if arg, ok := e.peek(); ok && arg == "!" && e.pos+1 < len(e.args) {
+		e.pos++
+		result, err := e.parseNot()
+		return !result, err
+	}
+	return e.parsePrimary()
+}
+
+func (e *testExpr) parsePrimary() (bool, error) {
+	arg, ok := e.peek()
+	if !ok {
+		return false, fmt.Errorf("argument expected")
+	}
+	// Бинарный оператор имеет приоритет: [ -f = -f ] сравнивает строки
+	if e.pos+2 < len(e.args) && testBinaryOps[e.args[e.pos+1]] {
+		left, op, right := arg, e.args[e.pos+1], e.args[e.pos+2]
+		e.pos += 3
+		return e.binary(left, op, right)
+	}
+	if arg == "(" && e.pos+1 < len(e.args) {
+		e.pos++
+		result, err := e.parseOr()
+		if err != nil {
+			return false, err
+		}
+		if next, ok := e.peek(); !ok || next != ")" {
+			return false, fmt.Errorf("')' expected")
+		}
+		e.pos++
+		return result, nil
+	}
+	if testUnaryOps[arg] && e.pos+1 < len(e.args) {
+		operand := e.args[e.pos+1]
+		e.pos += 2
+		return e.unary(arg, operand), nil
+	}
+	// Одиночный аргумент истинен, если не пуст
+	e.pos++
+	return arg != "", nil
+}
+
+func (e *testExpr) unary(op, operand string) bool {
+	switch op {
+	case "-z":
+		return operand == ""
+	case "-n":
+		return operand != ""
+	}
+	node, err := e.shell.vfs.FindNode(e.shell.absPath(operand))
+	if err != nil {
+		return false
+	}
+	switch op {
+	case "-f":
+		return !node.IsDir
+	case "-d":
+		return node.IsDir
+	case "-s":
+		return len(node.Content) > 0
+	}
+	// -e, -r, -w, -x: в VFS нет прав доступа, достаточно существования
+	return true
+}
+
+func (e *testExpr) binary(left, op, right string) (bool, error) {
+	switch op {
+	case "=", "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	case "<":
+		return left < right, nil
+	case ">":
+		return left > right, nil
+	case "-nt", "-ot":
+		leftNode, leftErr := e.shell.vfs.FindNode(e.shell.absPath(left))
+		rightNode, rightErr := e.shell.vfs.FindNode(e.shell.absPath(right))
+		if leftErr != nil || rightErr != nil {
+			// существующий файл считается новее несуществующего
+			if op == "-nt" {
+				return leftErr == nil && rightErr != nil, nil
+			}
+			return leftErr != nil && rightErr == nil, nil
+		}
+		if op == "-nt" {
+			return leftNode.ModTime.After(rightNode.ModTime), nil
+		}
+		return leftNode.ModTime.Before(rightNode.ModTime), nil
+	}
+	l, err := strconv.Atoi(left)
+	if err != nil {
+		return false, fmt.Errorf("%s: integer expression expected", left)
+	}
+	r, err := strconv.Atoi(right)
+	if err != nil {
+		return false, fmt.Errorf("%s: integer expression expected", right)
+	}
+	switch op {
+	case "-eq":
+		return l == r, nil
+	case "-ne":
+		return l != r, nil
+	case "-lt":
+		return l < r, nil
+	case "-le":
+		return l <= r, nil
+	case "-gt":
+		return l > r, nil
+	default: // -ge
+		return l >= r, nil
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/TimofeyChernyshev/MIREA-Configuration-management-1/vfs"
+)
+
+// Множители суффиксов размеров: K, M, G, T (степени 1024)
+var sizeSuffixes = map[byte]int64{'K': 1 << 10, 'M': 1 << 20, 'G': 1 << 30, 'T': 1 << 40}
+
+// Разбирает размер вида 512, 10K, 1.5M, 2G или 2GiB
+func parseSize(value string) (int64, error) {
+	number := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(value), "B"), "I")
+	multiplier := int64(1)
+	if number != "" {
+		if m, ok := sizeSuffixes[number[len(number)-1]]; ok {
+			multiplier = m
+			number = number[:len(number)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("invalid size '%s'", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// Размер в удобочитаемом виде, как du -h: 512, 1.5K, 12M
+func humanSize(size int64) string {
+	if size < 1024 {
+		return strconv.FormatInt(size, 10)
+	}
+	value := float64(size)
+	unit := -1
+	for value >= 1024 && unit < len("KMGTPE")-1 {
+		value /= 1024
+		unit++
+	}
+	// Округление вверх, как в GNU coreutils: 1023.9K выводится как 1.0M,
+	// значения меньше 10 - с одной цифрой после точки
+	if math.Ceil(value) >= 1024 && unit < len("KMGTPE")-1 {
+		value /= 1024
+		unit++
+	}
+	if value < 10 {
+		if rounded := math.Ceil(value*10) / 10; rounded < 10 {
+			return fmt.Sprintf("%.1f%c", rounded, "KMGTPE"[unit])
+		}
+	}
+	return fmt.Sprintf("%.0f%c", math.Ceil(value), "KMGTPE"[unit])
+}
+
+// Размер в килобайтах с округлением вверх
+func kilobytes(size int64) int64 {
+	return (size + 1023) / 1024
+}
+
+func (s *Shell) duCommand(args []string) {
+	// Выводит размер каталогов (и файлов с -a), вычисленный по содержимому файлов
+	opts, paths, ok := s.parseArgs("du", args)
+	if !ok {
+		return
+	}
+	maxDepth := -1
+	if opts.Has("d") {
+		n, err := strconv.Atoi(opts.Value("d"))
+		if err != nil || n < 0 {
+			s.errorf("du: invalid maximum depth '%s'\n", opts.Value("d"))
+			return
+		}
+		maxDepth = n
+	}
+	if opts.Has("s") {
+		maxDepth = 0
+	}
+	format := func(size int64) string {
+		switch {
+		case opts.Has("h"):
+			return humanSize(size)
+		case opts.Has("b"):
+			return strconv.FormatInt(size, 10)
+		}
+		return strconv.FormatInt(kilobytes(size), 10)
+	}
+	report := func(p string, size int64) {
+		if !s.emit(duResult{Path: p, Bytes: size}) {
+			fmt.Fprintf(s.out(), "%s\t%s\n", format(size), p)
+		}
+	}
+	// Размер узла; строки выводятся после содержимого каталога, как в du
+	var walk func(node *vfs.VFSNode, p string, depth int) int64
+	walk = func(node *vfs.VFSNode, p string, depth int) int64 {
+		size := int64(len(node.Content))
+		for _, child := range node.Children {
+			size += walk(child, strings.TrimSuffix(p, "/")+"/"+child.Name, depth+1)
+		}
+		if (node.IsDir || opts.Has("a") || depth == 0) && (maxDepth < 0 || depth <= maxDepth) {
+			report(p, size)
+		}
+		return size
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	for _, p := range paths {
+		node, err := s.vfs.FindNode(s.absPath(p))
+		if err != nil {
+			s.pathError("du", p, err)
+			continue
+		}
+		walk(node, p, 0)
+	}
+}
+
+func (s *Shell) dfCommand(args []string) {
+	// Выводит занятое место в VFS относительно ограничений
+	opts, _, ok := s.parseArgs("df", args)
+	if !ok {
+		return
+	}
+	usage := s.vfs.Usage()
+	limits := s.vfs.Limits
+	if s.emit(dfResult{
+		Filesystem: "vfs",
+		Bytes:      usage.Bytes,
+		MaxBytes:   limits.MaxBytes,
+		Nodes:      usage.Nodes,
+		MaxNodes:   limits.MaxNodes,
+		MountedOn:  "/",
+	}) {
+		return
+	}
+	// Процент использования с округлением вверх; без ограничения - "-"
+	percent := func(used, limit int64) string {
+		if limit <= 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%d%%", (used*100+limit-1)/limit)
+	}
+	// Размер, занятое и свободное место; без ограничения размер и свободное место - "-"
+	columns := func(used, limit int64, format func(int64) string) []string {
+		if limit <= 0 {
+			return []string{"-", format(used), "-", "-"}
+		}
+		return []string{format(limit), format(used), format(max(limit-used, 0)), percent(used, limit)}
+	}
+	var header, values []string
+	switch {
+	case opts.Has("i"):
+		header = []string{"Inodes", "IUsed", "IFree", "IUse%"}
+		values = columns(int64(usage.Nodes), int64(limits.MaxNodes), func(n int64) string { return strconv.FormatInt(n, 10) })
+	case opts.Has("h"):
+		header = []string{"Size", "Used", "Avail", "Use%"}
+		values = columns(usage.Bytes, limits.MaxBytes, humanSize)
+	default:
+		header = []string{"1K-blocks", "Used", "Available", "Use%"}
+		values = columns(usage.Bytes, limits.MaxBytes, func(n int64) string { return strconv.FormatInt(kilobytes(n), 10) })
+	}
+	fmt.Fprintf(s.out(), "%-10s %10s %10s %10s %5s %s\n", "Filesystem", header[0], header[1], header[2], header[3], "Mounted on")
+	fmt.Fprintf(s.out(), "%-10s %10s %10s %10s %5s %s\n", "vfs", values[0], values[1], values[2], values[3], "/")
+}
@@ -0,0 +1,729 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TimofeyChernyshev/MIREA-Configuration-management-1/vfs"
+)
+
+// Виды прерывания потока выполнения
+const (
+	flowBreak = iota + 1
+	flowContinue
+	flowReturn
+	flowExit
+)
+
+// Максимальная глубина вложенных вызовов функций и скриптов
+const maxCallDepth = 256
+
+// Отложенное прерывание выполнения (break/continue/return), обрабатывается циклами и функциями
+type flowControl struct {
+	kind  int
+	level int // на сколько вложенных циклов распространяется
+}
+
+// Выполняет введенную команду в текущей оболочке
+func (s *Shell) runInput(input string) {
+	s.runChunk(input)
+	s.flow = nil
+}
+
+// Выполняет текст, не сбрасывая return/exit, чтобы их мог обработать вызывающий скрипт
+func (s *Shell) runChunk(input string) {
+	defer s.acquire()()
+	list, err := parseScript(input)
+	if err != nil {
+		s.errorf("Error: %v\n", err)
+		s.status = 2
+		return
+	}
+	s.runList(list)
+}
+
+func (s *Shell) runList(list commandList) {
+	for _, node := range list {
+		s.runNode(node)
+		if s.flow != nil {
+			return
+		}
+	}
+}
+
+func (s *Shell) runNode(node commandNode) {
+	switch n := node.(type) {
+	case *simpleCommand:
+		s.runSimple(n)
+	case *andOrList:
+		s.runNode(n.first)
+		for _, item := range n.rest {
+			if s.flow != nil {
+				return
+			}
+			// && выполняет команду при успехе предыдущей, || - при неудаче
+			if (item.op == "&&") == (s.status == 0) {
+				s.runNode(item.cmd)
+			}
+		}
+	case *backgroundJob:
+		s.startJob(n.cmd)
+	case *negation:
+		s.runNode(n.cmd)
+		if s.status == 0 {
+			s.status = 1
+		} else {
+			s.status = 0
+		}
+	case *pipeline:
+		// Команды выполняются по очереди: вывод каждой накапливается в буфере
+		// и становится вводом следующей. Статус конвейера - статус последней команды
+		savedIn, savedOut := s.stdin, s.stdout
+		defer func() { s.stdin, s.stdout = savedIn, savedOut }()
+		for i, cmd := range n.cmds {
+			if i == len(n.cmds)-1 {
+				s.stdout = savedOut
+				s.runNode(cmd)
+				break
+			}
+			var buf bytes.Buffer
+			s.stdout = &buf
+			s.runNode(cmd)
+			s.stdin = &buf
+		}
+	case *ifClause:
+		for i, cond := range n.conds {
+			s.runList(cond)
+			if s.flow != nil {
+				return
+			}
+			if s.status == 0 {
+				s.runList(n.bodies[i])
+				return
+			}
+		}
+		s.status = 0
+		s.runList(n.elseBody)
+	case *forClause:
+		var values []string
+		for _, word := range n.words {
+			values = append(values, s.expandWord(word)...)
+		}
+		s.status = 0
+		s.loopDepth++
+		defer func() { s.loopDepth-- }()
+		for _, value := range values {
+			s.vars[n.name] = value
+			s.runList(n.body)
+			if s.loopInterrupted() {
+				break
+			}
+		}
+	case *whileClause:
+		s.loopDepth++
+		defer func() { s.loopDepth-- }()
+		status := 0
+		for {
+			s.runList(n.cond)
+			if s.flow != nil {
+				if s.loopInterrupted() {
+					break
+				}
+				continue
+			}
+			if (s.status == 0) == n.until {
+				break
+			}
+			s.runList(n.body)
+			status = s.status
+			if s.loopInterrupted() {
+				break
+			}
+		}
+		if s.flow == nil {
+			s.status = status
+		}
+	case *caseClause:
+		word := s.expandString(n.word)
+		s.status = 0
+		for _, item := range n.items {
+			for _, pattern := range item.patterns {
+				if globMatch(s.expandPattern(pattern), word) {
+					s.runList(item.body)
+					return
+				}
+			}
+		}
+	case *braceGroup:
+		s.runList(n.body)
+	case *funcDef:
+		s.functions[n.name] = n.body
+		s.status = 0
+	}
+}
+
+// Вызывает функцию: аргументы становятся позиционными параметрами $1..$9, $@, $#
+func (s *Shell) callFunction(name string, body commandNode, args []string) {
+	if s.callDepth >= maxCallDepth {
+		s.errorf("%s: maximum nesting level exceeded (%d)\n", name, maxCallDepth)
+		return
+	}
+	savedParams, savedLoopDepth := s.params, s.loopDepth
+	s.params, s.loopDepth = args, 0
+	s.callDepth++
+	defer func() {
+		s.params, s.loopDepth = savedParams, savedLoopDepth
+		s.callDepth--
+	}()
+	s.runNode(body)
+	if s.flow != nil && s.flow.kind == flowReturn {
+		s.flow = nil
+	}
+}
+
+// Выполняет псевдоним: его значение разбирается как команда, к которой добавляются аргументы
+func (s *Shell) runAlias(name, value string, args []string) {
+	text := value
+	for _, arg := range args {
+		text += " " + quoteWord(arg)
+	}
+	list, err := parseScript(text)
+	if err != nil {
+		s.errorf("%s: %v\n", name, err)
+		return
+	}
+	// Псевдоним не раскрывается повторно внутри себя: alias ls='ls -a'
+	s.activeAliases[name] = true
+	defer delete(s.activeAliases, name)
+	s.runList(list)
+}
+
+// Обрабатывает break/continue после итерации цикла. Возвращает true, если цикл нужно завершить
+func (s *Shell) loopInterrupted() bool {
+	if s.flow == nil {
+		return false
+	}
+	if s.flow.kind == flowReturn || s.flow.kind == flowExit {
+		return true // return и exit завершают все циклы
+	}
+	if s.flow.level > 1 {
+		// прерывание относится к внешнему циклу
+		s.flow.level--
+		return true
+	}
+	kind := s.flow.kind
+	s.flow = nil
+	return kind == flowBreak
+}
+
+func (s *Shell) runSimple(cmd *simpleCommand) {
+	// Между простыми командами фоновые задания получают возможность выполниться
+	s.yield()
+	s.expandErr = nil
+	s.substStatus = 0
+	for _, assign := range cmd.assigns {
+		name, value, _ := cutUnquoted(assign, '=')
+		s.vars[name] = s.expandString(value)
+	}
+	var words []string
+	for _, word := range cmd.words {
+		words = append(words, s.expandWord(word)...)
+	}
+	if err := s.expandErr; err != nil {
+		s.expandErr = nil
+		if s.jsonOutput() {
+			name := ""
+			if len(words) > 0 {
+				name = words[0]
+			}
+			s.runRecorded(name, nil, func() error {
+				s.status = 1
+				return err
+			})
+			return
+		}
+		s.errorf("Error: %v\n", err)
+		return
+	}
+	if len(words) == 0 {
+		// статус команды из одних присваиваний - статус последней подстановки $(...)
+		if len(cmd.assigns) > 0 {
+			s.status = s.substStatus
+		}
+		return
+	}
+	// В режиме JSON результат каждой команды, вывод которой не перенаправлен, выводится записью JSON
+	if s.jsonOutput() {
+		s.runRecorded(words[0], words[1:], func() error {
+			return s.executeCommand(words[0], words[1:])
+		})
+		return
+	}
+	if err := s.executeCommand(words[0], words[1:]); err != nil {
+		fmt.Fprintf(s.errOut(), "Error: %v\n", err)
+	}
+}
+
+// Значение переменной, включая специальные параметры
+func (s *Shell) lookupVar(name string) string {
+	switch name {
+	case "?":
+		return strconv.Itoa(s.status)
+	case "!":
+		if s.lastJob == 0 {
+			return ""
+		}
+		return strconv.Itoa(s.lastJob)
+	case "#":
+		return strconv.Itoa(len(s.params))
+	case "@", "*":
+		return strings.Join(s.params, " ")
+	}
+	// Позиционные параметры $1, $2, ...
+	if n, err := strconv.Atoi(name); err == nil && n > 0 {
+		if n <= len(s.params) {
+			return s.params[n-1]
+		}
+		return ""
+	}
+	return s.vars[name]
+}
+
+// Поле, полученное при раскрытии слова
+type expandedField struct {
+	text    string // значение без кавычек
+	pattern string // шаблон для сопоставления: символы из кавычек экранированы
+	glob    bool   // есть ли в шаблоне неэкранированные * ? [
+}
+
+// Раскрывает переменные в слове и удаляет кавычки.
+// Если split == true, результаты раскрытия вне кавычек разбиваются на поля по пробелам
+func (s *Shell) expandFields(raw string, split bool) []expandedField {
+	var fields []expandedField
+	var text, pattern strings.Builder
+	glob := false
+	present := false // поле существует, даже если пустое (например "")
+	flush := func() {
+		if present || text.Len() > 0 {
+			fields = append(fields, expandedField{text.String(), pattern.String(), glob})
+		}
+		text.Reset()
+		pattern.Reset()
+		glob = false
+		present = false
+	}
+	addLiteral := func(str string, quoted bool) {
+		text.WriteString(str)
+		if quoted {
+			pattern.WriteString(escapeGlob(str))
+			return
+		}
+		pattern.WriteString(str)
+		if strings.ContainsAny(str, "*?[") {
+			glob = true
+		}
+	}
+	addExpansion := func(value string) {
+		if !split {
+			addLiteral(value, false)
+			return
+		}
+		words := strings.Fields(value)
+		if len(words) == 0 {
+			if value != "" {
+				flush()
+			}
+			return
+		}
+		if strings.TrimLeft(value, " \t\n") != value {
+			flush()
+		}
+		for i, word := range words {
+			if i > 0 {
+				flush()
+			}
+			addLiteral(word, false)
+		}
+		if strings.TrimRight(value, " \t\n") != value {
+			flush()
+		}
+	}
+
+	runes := []rune(raw)
+	inDouble := false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case inDouble && r == '"':
+			inDouble = false
+		case inDouble && r == '\\':
+			if i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]) {
+				i++
+				if runes[i] != '\n' {
+					addLiteral(string(runes[i]), true)
+				}
+			} else {
+				addLiteral(`\`, true)
+			}
+		case r == '$' && inDouble && isAllParamsAt(runes, i):
+			// "$@" раскрывается в отдельное поле для каждого позиционного параметра
+			for j, param := range s.params {
+				if j > 0 {
+					flush()
+					present = true
+				}
+				addLiteral(param, true)
+			}
+			if len(s.params) == 0 && text.Len() == 0 {
+				present = false
+			}
+			if runes[i+1] == '{' {
+				i += 3
+			} else {
+				i++
+			}
+		case r == '$':
+			value, next, ok := s.expandParam(runes, i)
+			if !ok {
+				addLiteral("$", inDouble)
+				continue
+			}
+			i = next
+			if inDouble {
+				addLiteral(value, true)
+			} else {
+				addExpansion(value)
+			}
+		case inDouble:
+			addLiteral(string(r), true)
+		case r == '\\':
+			if i+1 < len(runes) {
+				i++
+				addLiteral(string(runes[i]), true)
+			}
+		case r == '\'':
+			present = true
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			addLiteral(string(runes[i+1:min(end, len(runes))]), true)
+			i = end
+		case r == '"':
+			present = true
+			inDouble = true
+		default:
+			addLiteral(string(r), false)
+		}
+	}
+	flush()
+	return fields
+}
+
+// Является ли $ в позиции i началом $@ или ${@}
+func isAllParamsAt(runes []rune, i int) bool {
+	rest := string(runes[i+1:])
+	return strings.HasPrefix(rest, "@") || strings.HasPrefix(rest, "{@}")
+}
+
+// Раскрывает параметр, начинающийся с $ в позиции i.
+// Возвращает значение и индекс последнего символа параметра
+func (s *Shell) expandParam(runes []rune, i int) (string, int, bool) {
+	if i+1 >= len(runes) {
+		return "", i, false
+	}
+	r := runes[i+1]
+	switch {
+	case r == '(':
+		end, ok := matchParen(runes, i+1)
+		if !ok {
+			return "", i, false
+		}
+		inner := string(runes[i+2 : end])
+		if strings.HasPrefix(inner, "(") && strings.HasSuffix(inner, ")") {
+			if close, ok := matchParen([]rune(inner), 0); ok && close == len([]rune(inner))-1 {
+				return s.expandArith(inner[1 : len(inner)-1]), end, true
+			}
+		}
+		return s.substituteCommand(inner), end, true
+	case r == '{':
+		end := i + 2
+		for end < len(runes) && runes[end] != '}' {
+			end++
+		}
+		if end >= len(runes) {
+			return "", i, false
+		}
+		name := string(runes[i+2 : end])
+		if strings.HasPrefix(name, "#") && len(name) > 1 {
+			return strconv.Itoa(len([]rune(s.lookupVar(name[1:])))), end, true
+		}
+		return s.lookupVar(name), end, true
+	case strings.ContainsRune("?#@*$!", r) || (r >= '0' && r <= '9'):
+		return s.lookupVar(string(r)), i + 1, true
+	case r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+		end := i + 1
+		for end+1 < len(runes) && isValidName(string(runes[i+1:end+2])) {
+			end++
+		}
+		return s.lookupVar(string(runes[i+1 : end+1])), end, true
+	}
+	return "", i, false
+}
+
+// Выполняет команды в подоболочке и возвращает их вывод без завершающих переводов строки
+func (s *Shell) substituteCommand(script string) string {
+	child := s.newSubshell()
+	var out bytes.Buffer
+	child.stdout = &out
+	child.runChunk(script)
+	s.substStatus = child.status
+	return strings.TrimRight(out.String(), "\n")
+}
+
+// Вычисляет $((...)). Выражение предварительно раскрывается как строка в двойных кавычках
+func (s *Shell) expandArith(expr string) string {
+	value, err := s.evalArith(s.expandString(`"` + strings.ReplaceAll(expr, `"`, `\"`) + `"`))
+	if err != nil {
+		if s.expandErr == nil {
+			s.expandErr = err
+		}
+		return ""
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+// Раскрывает слово в список аргументов: переменные, разбиение на поля и шаблоны по VFS
+func (s *Shell) expandWord(raw string) []string {
+	var result []string
+	for _, field := range s.expandFields(raw, true) {
+		if field.glob {
+			if matches := s.glob(field.pattern); len(matches) > 0 {
+				result = append(result, matches...)
+				continue
+			}
+		}
+		result = append(result, field.text)
+	}
+	return result
+}
+
+// Раскрывает слово в одну строку без разбиения на поля и шаблонов
+func (s *Shell) expandString(raw string) string {
+	var parts []string
+	for _, field := range s.expandFields(raw, false) {
+		parts = append(parts, field.text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Раскрывает слово в шаблон для сопоставления (case)
+func (s *Shell) expandPattern(raw string) string {
+	var parts []string
+	for _, field := range s.expandFields(raw, false) {
+		parts = append(parts, field.pattern)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Экранирует символы шаблона
+func escapeGlob(str string) string {
+	var b strings.Builder
+	for _, r := range str {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Удаляет экранирование из шаблона
+func unescapeGlob(pattern string) string {
+	var b strings.Builder
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '\\' && i+1 < len(runes) {
+			i++
+		}
+		b.WriteRune(runes[i])
+	}
+	return b.String()
+}
+
+// Содержит ли шаблон неэкранированные специальные символы
+func hasGlobMeta(pattern string) bool {
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			i++
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
+
+// Сопоставляет строку с шаблоном: * - любая последовательность, ? - любой символ,
+// [abc], [a-z], [!a] - класс символов, \x - символ x
+func globMatch(pattern, name string) bool {
+	p, n := []rune(pattern), []rune(name)
+	for len(p) > 0 {
+		switch p[0] {
+		case '*':
+			for len(p) > 0 && p[0] == '*' {
+				p = p[1:]
+			}
+			if len(p) == 0 {
+				return true
+			}
+			for i := 0; i <= len(n); i++ {
+				if globMatch(string(p), string(n[i:])) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(n) == 0 {
+				return false
+			}
+		case '[':
+			if len(n) == 0 {
+				return false
+			}
+			matched, rest, ok := matchClass(p, n[0])
+			if !ok {
+				// незакрытая скобка сравнивается как обычный символ
+				if n[0] != '[' {
+					return false
+				}
+				break
+			}
+			if !matched {
+				return false
+			}
+			p, n = rest, n[1:]
+			continue
+		case '\\':
+			if len(p) > 1 {
+				p = p[1:]
+			}
+			fallthrough
+		default:
+			if len(n) == 0 || n[0] != p[0] {
+				return false
+			}
+		}
+		p, n = p[1:], n[1:]
+	}
+	return len(n) == 0
+}
+
+// Сопоставляет символ с классом [...] в начале шаблона.
+// Возвращает результат, остаток шаблона и признак корректности класса
+func matchClass(p []rune, r rune) (bool, []rune, bool) {
+	i := 1
+	negate := false
+	if i < len(p) && (p[i] == '!' || p[i] == '^') {
+		negate = true
+		i++
+	}
+	matched := false
+	first := true
+	for i < len(p) && (first || p[i] != ']') {
+		first = false
+		lo := p[i]
+		if lo == '\\' && i+1 < len(p) {
+			i++
+			lo = p[i]
+		}
+		hi := lo
+		if i+2 < len(p) && p[i+1] == '-' && p[i+2] != ']' {
+			hi = p[i+2]
+			i += 2
+		}
+		if lo <= r && r <= hi {
+			matched = true
+		}
+		i++
+	}
+	if i >= len(p) {
+		return false, nil, false
+	}
+	return matched != negate, p[i+1:], true
+}
+
+// Абсолютный путь в VFS относительно текущей директории
+func (s *Shell) absPath(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		p = path.Join(s.currentPath, p)
+	}
+	return path.Clean(p)
+}
+
+// Раскрывает шаблон пути по VFS. Возвращает отсортированный список путей или nil
+func (s *Shell) glob(pattern string) []string {
+	type match struct {
+		display string
+		node    *vfs.VFSNode
+	}
+	start, err := s.vfs.FindNode(s.currentPath)
+	if err != nil {
+		return nil
+	}
+	matches := []match{{"", start}}
+	if strings.HasPrefix(pattern, "/") {
+		matches = []match{{"/", s.vfs.Root}}
+	}
+	join := func(dir, name string) string {
+		if dir == "" {
+			return name
+		}
+		if strings.HasSuffix(dir, "/") {
+			return dir + name
+		}
+		return dir + "/" + name
+	}
+	for _, part := range strings.Split(pattern, "/") {
+		if part == "" {
+			continue
+		}
+		var next []match
+		for _, m := range matches {
+			if !m.node.IsDir {
+				continue
+			}
+			if !hasGlobMeta(part) {
+				display := join(m.display, unescapeGlob(part))
+				if node, err := s.vfs.FindNode(s.absPath(display)); err == nil {
+					next = append(next, match{display, node})
+				}
+				continue
+			}
+			for _, child := range m.node.Children {
+				// скрытые файлы совпадают только с шаблоном, начинающимся с точки
+				if strings.HasPrefix(child.Name, ".") && !strings.HasPrefix(part, ".") {
+					continue
+				}
+				if globMatch(part, child.Name) {
+					next = append(next, match{join(m.display, child.Name), child})
+				}
+			}
+		}
+		matches = next
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(matches))
+	for _, m := range matches {
+		result = append(result, m.display)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TimofeyChernyshev/MIREA-Configuration-management-1/vfs"
+)
+
+// Файл, проверяемый выражением find
+type findFile struct {
+	path  string // путь в том виде, в каком он выводится
+	abs   string // абсолютный путь в VFS
+	node  *vfs.VFSNode
+	depth int // глубина относительно начального пути
+}
+
+// Проверка или действие выражения find: true - выражение истинно для файла
+type findPredicate func(f *findFile) bool
+
+// Разбор и выполнение выражения find
+type findExpr struct {
+	shell     *Shell
+	args      []string
+	pos       int
+	now       time.Time
+	maxDepth  int  // -maxdepth, -1 - без ограничения
+	minDepth  int  // -mindepth
+	hasAction bool // есть ли в выражении действие (-print, -exec, -delete)
+	depth     bool // обход в глубину: содержимое каталога перед самим каталогом
+	failed    bool // были ли ошибки при выполнении действий
+}
+
+func (s *Shell) findCommand(args []string) {
+	// Ищет файлы в дереве VFS по выражению
+	i := 0
+	for i < len(args) && !strings.HasPrefix(args[i], "-") && args[i] != "(" && args[i] != "!" {
+		i++
+	}
+	roots := args[:i]
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	e := &findExpr{shell: s, args: args[i:], now: time.Now(), maxDepth: -1}
+	match, err := e.parse()
+	if err != nil {
+		s.errorf("find: %v\n", err)
+		return
+	}
+	for _, root := range roots {
+		abs := s.absPath(root)
+		node, err := s.vfs.FindNode(abs)
+		if err != nil {
+			s.pathError("find", root, err)
+			e.failed = true
+			continue
+		}
+		e.walk(&findFile{path: root, abs: abs, node: node}, match)
+	}
+	if e.failed {
+		s.status = 1
+	}
+}
+
+// Разбирает выражение целиком. Без действий найденные файлы выводятся, как с -print
+func (e *findExpr) parse() (findPredicate, error) {
+	match := findPredicate(func(*findFile) bool { return true })
+	if len(e.args) > 0 {
+		var err error
+		if match, err = e.parseOr(); err != nil {
+			return nil, err
+		}
+		if e.pos < len(e.args) {
+			return nil, fmt.Errorf("unexpected argument '%s'", e.args[e.pos])
+		}
+	}
+	if !e.hasAction {
+		test := match
+		match = func(f *findFile) bool {
+			return test(f) && e.print(f, "\n")
+		}
+	}
+	return match, nil
+}
+
+// Обходит файл и его содержимое с учетом -mindepth, -maxdepth и -depth
+func (e *findExpr) walk(f *findFile, match findPredicate) {
+	if !e.depth && f.depth >= e.minDepth {
+		match(f)
+	}
+	if f.node.IsDir && (e.maxDepth < 0 || f.depth < e.maxDepth) {
+		// Копия списка: -delete и -exec могут менять содержимое каталога
+		for _, child := range slices.Clone(f.node.Children) {
+			childPath := f.path
+			if !strings.HasSuffix(childPath, "/") {
+				childPath += "/"
+			}
+			e.walk(&findFile{
+				path:  childPath + child.Name,
+				abs:   path.Join(f.abs, child.Name),
+				node:  child,
+				depth: f.depth + 1,
+			}, match)
+		}
+	}
+	if e.depth && f.depth >= e.minDepth {
+		match(f)
+	}
+}
+
+func (e *findExpr) peek() (string, bool) {
+	if e.pos >= len(e.args) {
+		return "", false
+	}
+	return e.args[e.pos], true
+}
+
+// expr -o expr
+func (e *findExpr) parseOr() (findPredicate, error) {
+	left, err := e.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if arg, ok := e.peek(); !ok || arg != "-o" && arg != "-or" {
+			return left, nil
+		}
+		e.pos++
+		right, err := e.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(f *findFile) bool { return l(f) || right(f) }
+	}
+}
+
+// expr [-a] expr: соседние выражения без оператора тоже объединяются через И
+func (e *findExpr) parseAnd() (findPredicate, error) {
+	left, err := e.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		arg, ok := e.peek()
+		if !ok || arg == "-o" || arg == "-or" || arg == ")" {
+			return left, nil
+		}
+		if arg == "-a" || arg == "-and" {
+			e.pos++
+		}
+		right, err := e.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(f *findFile) bool { return l(f) && right(f) }
+	}
+}
+
+// ! expr
+func (e *findExpr) parseNot() (findPredicate, error) {
+	if arg, ok := e.peek(); ok && (arg == "!" || arg == "-not") {
+		e.pos++
+		operand, err := e.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(f *findFile) bool { return !operand(f) }, nil
+	}
+	return e.parsePrimary()
+}
+
+// Следующий аргумент проверки или действия
+func (e *findExpr) operand(name string) (string, error) {
+	if e.pos >= len(e.args) {
+		return "", fmt.Errorf("missing argument to '%s'", name)
+	}
+	e.pos++
+	return e.args[e.pos-1], nil
+}
+
+func (e *findExpr) parsePrimary() (findPredicate, error) {
+	arg, ok := e.peek()
+	if !ok {
+		return nil, fmt.Errorf("expected an expression")
+	}
+	e.pos++
+	switch arg {
+	case "(":
+		inner, err := e.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := e.peek(); !ok || next != ")" {
+			return nil, fmt.Errorf("')' expected")
+		}
+		e.pos++
+		return inner, nil
+	case "-true":
+		return func(*findFile) bool { return true }, nil
+	case "-false":
+		return func(*findFile) bool { return false }, nil
+	case "-name", "-iname":
+		pattern, err := e.operand(arg)
+		if err != nil {
+			return nil, err
+		}
+		// Шаблон сравнивается так же, как при раскрытии имен файлов в оболочке: [!...], классы
+		// и экранирование поддерживаются, а незакрытая скобка - обычный символ, так что неверных шаблонов нет
+		fold := arg == "-iname"
+		if fold {
+			pattern = strings.ToLower(pattern)
+		}
+		return func(f *findFile) bool {
+			name := path.Base(f.path)
+			if fold {
+				name = strings.ToLower(name)
+			}
+			return globMatch(pattern, name)
+		}, nil
+	case "-type":
+		kind, err := e.operand(arg)
+		if err != nil {
+			return nil, err
+		}
+		if kind != "f" && kind != "d" {
+			return nil, fmt.Errorf("-type: unknown argument '%s'", kind)
+		}
+		return func(f *findFile) bool { return f.node.IsDir == (kind == "d") }, nil
+	case "-size":
+		value, err := e.operand(arg)
+		if err != nil {
+			return nil, err
+		}
+		return parseFindSize(value)
+	case "-mtime":
+		value, err := e.operand(arg)
+		if err != nil {
+			return nil, err
+		}
+		cmp, days, err := parseFindNumber(value)
+		if err != nil {
+			return nil, fmt.Errorf("-mtime: invalid argument '%s'", value)
+		}
+		return func(f *findFile) bool {
+			// Возраст в полных сутках, как в GNU find
+			age := int64(e.now.Sub(f.node.ModTime) / (24 * time.Hour))
+			return compareFindNumber(cmp, age, days)
+		}, nil
+	case "-user":
+		owner, err := e.operand(arg)
+		if err != nil {
+			return nil, err
+		}
+		return func(f *findFile) bool { return f.node.Owner == owner }, nil
+	case "-newer":
+		file, err := e.operand(arg)
+		if err != nil {
+			return nil, err
+		}
+		ref, err := e.shell.vfs.FindNode(e.shell.absPath(file))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, vfs.Reason(err))
+		}
+		return func(f *findFile) bool { return f.node.ModTime.After(ref.ModTime) }, nil
+	case "-maxdepth", "-mindepth":
+		value, err := e.operand(arg)
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%s: invalid argument '%s'", arg, value)
+		}
+		if arg == "-maxdepth" {
+			e.maxDepth = n
+		} else {
+			e.minDepth = n
+		}
+		return func(*findFile) bool { return true }, nil
+	case "-depth":
+		e.depth = true
+		return func(*findFile) bool { return true }, nil
+	case "-print":
+		e.hasAction = true
+		return func(f *findFile) bool { return e.print(f, "\n") }, nil
+	case "-print0":
+		e.hasAction = true
+		return func(f *findFile) bool { return e.print(f, "\x00") }, nil
+	case "-delete":
+		// Содержимое каталогов удаляется раньше самих каталогов
+		e.hasAction = true
+		e.depth = true
+		return e.delete, nil
+	case "-exec":
+		e.hasAction = true
+		return e.parseExec()
+	}
+	return nil, fmt.Errorf("unknown predicate '%s'", arg)
+}
+
+// -exec COMMAND ; - выполняет команду, заменяя {} путем файла. Истинно при статусе 0
+func (e *findExpr) parseExec() (findPredicate, error) {
+	end := slices.Index(e.args[e.pos:], ";")
+	if end <= 0 {
+		return nil, fmt.Errorf("missing argument to '-exec'")
+	}
+	command := e.args[e.pos : e.pos+end]
+	e.pos += end + 1
+	return func(f *findFile) bool {
+		s := e.shell
+		words := make([]string, len(command))
+		for i, word := range command {
+			words[i] = strings.ReplaceAll(word, "{}", f.path)
+		}
+		if err := s.executeCommand(words[0], words[1:]); err != nil {
+			fmt.Fprintf(s.errOut(), "find: %s: %v\n", words[0], err)
+		}
+		ok := s.status == 0
+		s.status = 0
+		return ok
+	}, nil
+}
+
+func (e *findExpr) print(f *findFile, terminator string) bool {
+	s := e.shell
+	if terminator == "\n" && s.emit(findResult{Path: f.path, Type: nodeType(f.node.IsDir)}) {
+		return true
+	}
+	fmt.Fprint(s.out(), f.path+terminator)
+	return true
+}
+
+func (e *findExpr) delete(f *findFile) bool {
+	s := e.shell
+	if f.node == s.vfs.Root {
+		s.pathError("find", f.path, vfs.ErrPermission)
+		e.failed = true
+		return false
+	}
+	if f.node.IsDir && len(f.node.Children) > 0 {
+		s.pathError("find", f.path, vfs.ErrNotEmpty)
+		e.failed = true
+		return false
+	}
+	if err := s.vfs.RemoveNode(f.abs); err != nil {
+		s.pathError("find", f.path, err)
+		e.failed = true
+		return false
+	}
+	return true
+}
+
+// Разбирает числовой аргумент find: +N - больше N, -N - меньше N, N - ровно N
+func parseFindNumber(value string) (cmp int, n int64, err error) {
+	switch {
+	case strings.HasPrefix(value, "+"):
+		cmp, value = 1, value[1:]
+	case strings.HasPrefix(value, "-"):
+		cmp, value = -1, value[1:]
+	}
+	n, err = strconv.ParseInt(value, 10, 64)
+	if err == nil && n < 0 {
+		err = fmt.Errorf("negative number")
+	}
+	return cmp, n, err
+}
+
+func compareFindNumber(cmp int, value, n int64) bool {
+	switch cmp {
+	case 1:
+		return value > n
+	case -1:
+		return value < n
+	}
+	return value == n
+}
+
+// Единицы -size: c - байты, w - слова по 2 байта, b - блоки по 512 байт (по умолчанию), k, M, G
+var findSizeUnits = map[byte]int64{'c': 1, 'w': 2, 'b': 512, 'k': 1 << 10, 'M': 1 << 20, 'G': 1 << 30}
+
+// -size [+-]N[cwbkMG]: размер округляется вверх до целого числа единиц, как в GNU find
+func parseFindSize(value string) (findPredicate, error) {
+	unit := int64(512)
+	number := value
+	if number != "" {
+		if u, ok := findSizeUnits[number[len(number)-1]]; ok {
+			unit = u
+			number = number[:len(number)-1]
+		}
+	}
+	cmp, n, err := parseFindNumber(number)
+	if err != nil {
+		return nil, fmt.Errorf("-size: invalid argument '%s'", value)
+	}
+	return func(f *findFile) bool {
+		size := int64(len(f.node.Content))
+		return compareFindNumber(cmp, (size+unit-1)/unit, n)
+	}, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// Строковое представление флага, например "-n, --lines=N"
+func flagString(f FlagSpec) string {
+	var names []string
+	if f.Short != "" {
+		names = append(names, "-"+f.Short)
+	}
+	if f.Long != "" {
+		names = append(names, "--"+f.Long)
+	}
+	str := strings.Join(names, ", ")
+	if f.Arg != "" {
+		if f.Long != "" {
+			str += "=" + f.Arg
+		} else {
+			str += " " + f.Arg
+		}
+	}
+	return str
+}
+
+// Краткая справка по команде, выводится командами help CMD и CMD --help
+func formatHelp(info CommandInfo) string {
+	var b strings.Builder
+	usage := info.Usage
+	if usage == "" {
+		usage = info.Name
+	}
+	fmt.Fprintf(&b, "Usage: %s\n", usage)
+	if info.Short != "" {
+		fmt.Fprintf(&b, "%s\n", info.Short)
+	}
+	if info.Long != "" {
+		fmt.Fprintf(&b, "\n%s\n", info.Long)
+	}
+	if len(info.Flags) > 0 {
+		b.WriteString("\nOptions:\n")
+		w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		for _, f := range info.Flags {
+			fmt.Fprintf(w, "  %s\t%s\n", flagString(f), f.Help)
+		}
+		w.Flush()
+	}
+	return b.String()
+}
+
+// Страница руководства в стиле man
+func formatManPage(info CommandInfo) string {
+	var b strings.Builder
+	b.WriteString("NAME\n")
+	if info.Short != "" {
+		fmt.Fprintf(&b, "    %s - %s\n", info.Name, info.Short)
+	} else {
+		fmt.Fprintf(&b, "    %s\n", info.Name)
+	}
+	usage := info.Usage
+	if usage == "" {
+		usage = info.Name
+	}
+	fmt.Fprintf(&b, "\nSYNOPSIS\n    %s\n", usage)
+	if info.Long != "" {
+		fmt.Fprintf(&b, "\nDESCRIPTION\n    %s\n", info.Long)
+	}
+	if len(info.Flags) > 0 {
+		b.WriteString("\nOPTIONS\n")
+		for _, f := range info.Flags {
+			fmt.Fprintf(&b, "    %s\n        %s\n", flagString(f), f.Help)
+		}
+	}
+	return b.String()
+}
+
+// Содержит ли список аргументов флаг --help (до разделителя --, а для команд
+// со StopAtOperand - до первого операнда)
+func wantsHelp(info CommandInfo, args []string) bool {
+	for _, arg := range args {
+		if arg == "--" || info.StopAtOperand && !strings.HasPrefix(arg, "-") {
+			return false
+		}
+		if arg == "--help" {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Shell) helpCommand(args []string) {
+	// Выводит список команд или справку по указанной команде
+	if len(args) == 0 {
+		fmt.Fprintln(s.out(), "Commands:")
+		w := tabwriter.NewWriter(s.out(), 0, 0, 2, ' ', 0)
+		for _, cmd := range s.Commands() {
+			info := cmd.Info()
+			fmt.Fprintf(w, "  %s\t%s\n", info.Name, info.Short)
+		}
+		w.Flush()
+		fmt.Fprintln(s.out(), "Type 'help COMMAND', 'man COMMAND' or 'COMMAND --help' for more information.")
+		return
+	}
+	for _, name := range args {
+		cmd, exists := s.commands[name]
+		if !exists {
+			s.errorf("help: no help topics match '%s'\n", name)
+			continue
+		}
+		fmt.Fprint(s.out(), formatHelp(cmd.Info()))
+	}
+}
+
+func (s *Shell) manCommand(args []string) {
+	// Выводит страницу руководства по команде
+	if len(args) == 0 {
+		s.errorf("man: what manual page do you want?\n")
+		return
+	}
+	for i, name := range args {
+		cmd, exists := s.commands[name]
+		if !exists {
+			s.errorf("man: no manual entry for %s\n", name)
+			continue
+		}
+		if i > 0 {
+			fmt.Fprintln(s.out())
+		}
+		fmt.Fprint(s.out(), formatManPage(cmd.Info()))
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Размер истории по умолчанию
+const defaultHistorySize = 500
+
+// Имя файла истории в домашнем каталоге пользователя
+const historyFileName = ".vfs_history"
+
+// История команд. Номера записей сквозные: после удаления старых записей номера не сдвигаются
+type History struct {
+	entries []string
+	first   int    // номер первой хранимой записи
+	limit   int    // максимальное число записей, 0 - без ограничения
+	path    string // файл истории на диске, пусто - только в памяти
+}
+
+func NewHistory(limit int) *History {
+	return &History{first: 1, limit: limit}
+}
+
+// Файл истории по умолчанию: ~/.vfs_history
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, historyFileName)
+}
+
+// Загружает историю из файла и запоминает его для последующего сохранения.
+// Отсутствующий файл не является ошибкой
+func (h *History) Load(path string) error {
+	h.path = path
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		h.append(unescapeHistory(scanner.Text()))
+	}
+	return scanner.Err()
+}
+
+// Сохраняет историю в файл. Многострочные команды записываются в одну строку с экранированием
+func (h *History) Save() error {
+	if h.path == "" {
+		return nil
+	}
+	var b strings.Builder
+	for _, entry := range h.entries {
+		b.WriteString(escapeHistory(entry))
+		b.WriteByte('\n')
+	}
+	return os.WriteFile(h.path, []byte(b.String()), 0o600)
+}
+
+// Добавляет команду в историю и сохраняет ее на диск. Пустые строки не запоминаются
+func (h *History) Add(line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	h.append(line)
+	return h.Save()
+}
+
+func (h *History) append(line string) {
+	h.entries = append(h.entries, line)
+	h.trim()
+}
+
+// Удаляет самые старые записи сверх ограничения
+func (h *History) trim() {
+	if h.limit > 0 && len(h.entries) > h.limit {
+		drop := len(h.entries) - h.limit
+		h.entries = h.entries[drop:]
+		h.first += drop
+	}
+}
+
+// Меняет ограничение размера истории
+func (h *History) SetLimit(limit int) {
+	h.limit = limit
+	h.trim()
+}
+
+// Очищает историю
+func (h *History) Clear() error {
+	h.first += len(h.entries)
+	h.entries = nil
+	return h.Save()
+}
+
+// Записи истории в порядке добавления
+func (h *History) Entries() []string {
+	return h.entries
+}
+
+// Запись с номером n
+func (h *History) Get(n int) (string, bool) {
+	if n < h.first || n >= h.first+len(h.entries) {
+		return "", false
+	}
+	return h.entries[n-h.first], true
+}
+
+// Раскрывает ссылки на историю: !! - предыдущая команда, !n - команда с номером n,
+// !-n - n-я с конца, !prefix - последняя команда, начинающаяся с prefix.
+// Внутри одинарных кавычек и после \ символ ! не раскрывается
+func (h *History) Expand(line string) (string, error) {
+	if !strings.Contains(line, "!") {
+		return line, nil
+	}
+	var b strings.Builder
+	runes := []rune(line)
+	inSingle, inDouble := false, false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case inSingle:
+			if r == '\'' {
+				inSingle = false
+			}
+			b.WriteRune(r)
+			continue
+		case r == '\\' && i+1 < len(runes):
+			b.WriteRune(r)
+			b.WriteRune(runes[i+1])
+			i++
+			continue
+		case r == '\'' && !inDouble:
+			inSingle = true
+		case r == '"':
+			inDouble = !inDouble
+		case r == '!' && i+1 < len(runes):
+			end, entry, err := h.event(runes, i)
+			if err != nil {
+				return "", err
+			}
+			if end > i {
+				b.WriteString(entry)
+				i = end
+				continue
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), nil
+}
+
+// Разбирает ссылку на историю, начинающуюся с ! в позиции i.
+// Возвращает индекс последнего символа ссылки (i, если ! не является ссылкой) и найденную команду
+func (h *History) event(runes []rune, i int) (int, string, error) {
+	next := runes[i+1]
+	// "! ", "!=" и "!(" не являются ссылками на историю
+	if strings.ContainsRune(" \t\n=(\"", next) {
+		return i, "", nil
+	}
+	end := i + 1
+	for end+1 < len(runes) && !strings.ContainsRune(" \t\n;&|()<>'\"", runes[end+1]) {
+		end++
+	}
+	spec := string(runes[i+1 : end+1])
+	if next == '!' {
+		end, spec = i+1, "!"
+	} else if n, err := strconv.Atoi(spec); err == nil {
+		var entry string
+		var ok bool
+		if n < 0 {
+			entry, ok = h.Get(h.first + len(h.entries) + n)
+		} else {
+			entry, ok = h.Get(n)
+		}
+		if !ok {
+			return 0, "", fmt.Errorf("!%s: event not found", spec)
+		}
+		return end, entry, nil
+	}
+	if spec == "!" {
+		if len(h.entries) == 0 {
+			return 0, "", fmt.Errorf("!!: event not found")
+		}
+		return end, h.entries[len(h.entries)-1], nil
+	}
+	for j := len(h.entries) - 1; j >= 0; j-- {
+		if strings.HasPrefix(h.entries[j], spec) {
+			return end, h.entries[j], nil
+		}
+	}
+	return 0, "", fmt.Errorf("!%s: event not found", spec)
+}
+
+func escapeHistory(entry string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(entry)
+}
+
+func unescapeHistory(line string) string {
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) {
+			i++
+			if line[i] == 'n' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(line[i])
+			}
+			continue
+		}
+		b.WriteByte(line[i])
+	}
+	return b.String()
+}
+
+// Обрабатывает строку интерактивного ввода: раскрывает ссылки на историю и запоминает команду.
+// Раскрытая команда выводится перед выполнением. Возвращает false, если ссылка не найдена
+func (s *Shell) recordInput(input string) (string, bool) {
+	expanded, err := s.history.Expand(input)
+	if err != nil {
+		s.errorf("%v\n", err)
+		return "", false
+	}
+	if expanded != input {
+		fmt.Fprintln(s.out(), expanded)
+	}
+	// HISTSIZE меняет ограничение размера истории
+	if size, err := strconv.Atoi(s.vars["HISTSIZE"]); err == nil && size >= 0 {
+		s.history.SetLimit(size)
+	}
+	if err := s.history.Add(expanded); err != nil {
+		fmt.Fprintf(s.errOut(), "history: %v\n", err)
+	}
+	return expanded, true
+}
+
+func (s *Shell) historyCommand(args []string) {
+	// Выводит историю команд или последние N записей
+	opts, operands, ok := s.parseArgs("history", args)
+	if !ok {
+		return
+	}
+	if opts.Has("c") {
+		if err := s.history.Clear(); err != nil {
+			s.errorf("history: %v\n", err)
+		}
+		return
+	}
+	entries := s.history.Entries()
+	if len(operands) > 0 {
+		n, err := strconv.Atoi(operands[0])
+		if err != nil || n < 0 {
+			s.errorf("history: %s: numeric argument required\n", operands[0])
+			return
+		}
+		if n < len(entries) {
+			entries = entries[len(entries)-n:]
+		}
+	}
+	first := s.history.first + len(s.history.Entries()) - len(entries)
+	for i, entry := range entries {
+		fmt.Fprintf(s.out(), "%5d  %s\n", first+i, entry)
+	}
+}
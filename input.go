@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+// Вторичное приглашение к вводу (PS2) для продолжения команды на следующей строке
+const secondaryPrompt = "> "
+
+// Источник строк ввода: выводит приглашение и возвращает очередную строку
+type lineSource func(prompt string) (string, bool)
+
+// Проверяет, закончен ли ввод. Незакрытые кавычки, \ в конце строки
+// и незакрытые составные команды требуют продолжения ввода
+func inputComplete(input string) (bool, error) {
+	if _, err := parseScript(input); err != nil {
+		var syntaxErr *SyntaxError
+		if errors.As(err, &syntaxErr) && syntaxErr.Incomplete {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Считывает одну законченную команду, возможно состоящую из нескольких строк.
+// Первая строка запрашивается с приглашением prompt, последующие - с secondaryPrompt
+func readInput(next lineSource, prompt string) (string, error) {
+	var lines []string
+	for {
+		line, ok := next(prompt)
+		if !ok {
+			if len(lines) == 0 {
+				return "", io.EOF
+			}
+			return strings.Join(lines, "\n"), &SyntaxError{Msg: "unexpected end of file"}
+		}
+		lines = append(lines, line)
+		input := strings.Join(lines, "\n")
+		complete, err := inputComplete(input)
+		if err != nil {
+			return input, err
+		}
+		if complete {
+			return input, nil
+		}
+		prompt = secondaryPrompt
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Фоновое задание, запущенное через &
+type job struct {
+	id     int
+	done   chan struct{} // закрывается по завершении задания
+	status int
+}
+
+// Фоновые задания оболочки, общие для нее и всех ее дочерних контекстов.
+// Команды основной оболочки и фоновых заданий выполняются по очереди: выполняющий
+// команды удерживает mu и отпускает его только на время ожидания (sleep, wait,
+// tail -f) и между простыми командами
+type jobControl struct {
+	mu   sync.Mutex
+	jobs []*job
+}
+
+// Захватывает блокировку выполнения, если контекст ее еще не удерживает.
+// Возвращает функцию, освобождающую захваченную блокировку
+func (s *Shell) acquire() func() {
+	if s.locked {
+		return func() {}
+	}
+	s.jobs.mu.Lock()
+	s.locked = true
+	return func() {
+		s.locked = false
+		s.jobs.mu.Unlock()
+	}
+}
+
+// Дает выполниться ожидающим фоновым заданиям или основной оболочке
+func (s *Shell) yield() {
+	if s.locked {
+		s.jobs.mu.Unlock()
+		s.jobs.mu.Lock()
+	}
+}
+
+// Канал прерываний Ctrl-C (SIGINT) на время ожидания и функция, отключающая перехват.
+// Фоновые задания сигнал не получают: их канал nil
+func (s *Shell) interrupts() (<-chan os.Signal, func()) {
+	if s.background {
+		return nil, func() {}
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	return interrupt, func() { signal.Stop(interrupt) }
+}
+
+// Ждет закрытия done, отпустив блокировку выполнения. Возвращает false,
+// если ожидание прервано сигналом из interrupt
+func (s *Shell) await(done <-chan struct{}, interrupt <-chan os.Signal) bool {
+	if s.locked {
+		s.jobs.mu.Unlock()
+		defer s.jobs.mu.Lock()
+	}
+	select {
+	case <-done:
+		return true
+	case <-interrupt:
+		return false
+	}
+}
+
+// Канал, закрываемый через d
+func after(d time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	time.AfterFunc(d, func() { close(done) })
+	return done
+}
+
+// Запускает команду в фоновой подоболочке. Стандартный ввод задания пуст,
+// номер задания доступен как $!
+func (s *Shell) startJob(node commandNode) {
+	child := s.newSubshell()
+	child.background = true
+	child.locked = false
+	child.stdin = strings.NewReader("")
+	// Результаты задания не относятся к команде, во время которой оно запущено
+	child.record = nil
+	j := &job{id: len(s.jobs.jobs) + 1, done: make(chan struct{})}
+	s.jobs.jobs = append(s.jobs.jobs, j)
+	s.lastJob = j.id
+	go func() {
+		defer close(j.done)
+		defer child.acquire()()
+		child.runNode(node)
+		j.status = child.status
+	}()
+	s.status = 0
+}
+
+func (s *Shell) waitCommand(args []string) {
+	// Ожидает завершения фоновых заданий
+	_, ids, ok := s.parseArgs("wait", args)
+	if !ok {
+		return
+	}
+	interrupt, stop := s.interrupts()
+	defer stop()
+	if len(ids) == 0 {
+		// Без аргументов - все задания, статус 0
+		for _, j := range s.jobs.jobs {
+			if !s.await(j.done, interrupt) {
+				s.status = 130
+				return
+			}
+		}
+		s.status = 0
+		return
+	}
+	status := 0
+	for _, id := range ids {
+		n, err := strconv.Atoi(strings.TrimPrefix(id, "%"))
+		if err != nil || n < 1 || n > len(s.jobs.jobs) {
+			s.errorf("wait: %s: no such job\n", id)
+			status = 127
+			continue
+		}
+		j := s.jobs.jobs[n-1]
+		if !s.await(j.done, interrupt) {
+			s.status = 130
+			return
+		}
+		status = j.status
+	}
+	s.status = status
+}
+
+// Разбирает интервал sleep: число секунд, возможно дробное, с суффиксом s, m, h или d
+func parseSleepInterval(arg string) (time.Duration, error) {
+	units := map[byte]float64{'s': 1, 'm': 60, 'h': 3600, 'd': 86400}
+	number, unit := arg, 1.0
+	if n := len(arg); n > 0 && units[arg[n-1]] != 0 {
+		number, unit = arg[:n-1], units[arg[n-1]]
+	}
+	seconds, err := strconv.ParseFloat(number, 64)
+	if err != nil || seconds < 0 || strings.ContainsAny(number, "xXnNiI") {
+		return 0, fmt.Errorf("invalid time interval '%s'", arg)
+	}
+	return time.Duration(seconds * unit * float64(time.Second)), nil
+}
+
+func (s *Shell) sleepCommand(args []string) {
+	// Приостанавливает выполнение на сумму интервалов; фоновые задания в это время работают
+	_, intervals, ok := s.parseArgs("sleep", args)
+	if !ok {
+		return
+	}
+	if len(intervals) == 0 {
+		s.errorf("sleep: missing operand\n")
+		return
+	}
+	var total time.Duration
+	for _, arg := range intervals {
+		d, err := parseSleepInterval(arg)
+		if err != nil {
+			s.errorf("sleep: %v\n", err)
+			return
+		}
+		total += d
+	}
+	interrupt, stop := s.interrupts()
+	defer stop()
+	if !s.await(after(total), interrupt) {
+		s.status = 130
+		return
+	}
+	s.status = 0
+}
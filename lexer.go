@@ -0,0 +1,260 @@
+package main
+
+import (
+	"strings"
+)
+
+// Синтаксическая ошибка командной строки.
+// Incomplete означает, что ввод оборван (незакрытая кавычка, \ в конце) и может быть продолжен
+type SyntaxError struct {
+	Msg        string
+	Incomplete bool
+}
+
+func (e *SyntaxError) Error() string {
+	return "syntax error: " + e.Msg
+}
+
+// Состояния лексера
+const (
+	stateBlank  = iota // между словами
+	stateWord          // внутри слова без кавычек
+	stateSingle        // внутри '...'
+	stateDouble        // внутри "..."
+)
+
+// Виды лексем
+const (
+	tokWord = iota // слово (кавычки и экранирование сохранены)
+	tokOp          // оператор: ; & && || | ( ) ;; или перевод строки
+)
+
+// Лексема командной строки
+type token struct {
+	kind int
+	text string
+}
+
+// Операторы, разделяющие команды. Более длинные должны идти раньше
+var operators = []string{";;", "&&", "||", ";", "&", "|", "(", ")", "\n"}
+
+// Разбивает строку на слова и операторы, сохраняя кавычки и экранирование в словах как есть.
+// Соседние сегменты в кавычках и без них образуют одно слово: "a"b'c' -> одно слово
+func lex(input string) ([]token, error) {
+	var tokens []token
+	var word strings.Builder
+	state := stateBlank
+	runes := []rune(input)
+	endWord := func() {
+		if state == stateWord {
+			tokens = append(tokens, token{tokWord, word.String()})
+			word.Reset()
+		}
+		state = stateBlank
+	}
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch state {
+		case stateBlank, stateWord:
+			if op := matchOperator(runes[i:]); op != "" {
+				endWord()
+				tokens = append(tokens, token{tokOp, op})
+				i += len([]rune(op)) - 1
+				continue
+			}
+			switch {
+			case r == ' ' || r == '\t' || r == '\r':
+				endWord()
+			case r == '$' && i+1 < len(runes) && runes[i+1] == '(':
+				end, ok := matchParen(runes, i+1)
+				if !ok {
+					return nil, &SyntaxError{Msg: "unterminated command substitution", Incomplete: true}
+				}
+				word.WriteString(string(runes[i : end+1]))
+				i = end
+				state = stateWord
+			case r == '#' && state == stateBlank:
+				// комментарий до конца строки
+				for i+1 < len(runes) && runes[i+1] != '\n' {
+					i++
+				}
+			case r == '\\':
+				if i+1 >= len(runes) {
+					return nil, &SyntaxError{Msg: "unexpected end of input after '\\'", Incomplete: true}
+				}
+				if runes[i+1] == '\n' {
+					i++ // продолжение строки
+					continue
+				}
+				word.WriteRune(r)
+				word.WriteRune(runes[i+1])
+				i++
+				state = stateWord
+			case r == '\'':
+				word.WriteRune(r)
+				state = stateSingle
+			case r == '"':
+				word.WriteRune(r)
+				state = stateDouble
+			default:
+				word.WriteRune(r)
+				state = stateWord
+			}
+		case stateSingle:
+			word.WriteRune(r)
+			if r == '\'' {
+				state = stateWord
+			}
+		case stateDouble:
+			if r == '$' && i+1 < len(runes) && runes[i+1] == '(' {
+				end, ok := matchParen(runes, i+1)
+				if !ok {
+					return nil, &SyntaxError{Msg: "unterminated command substitution", Incomplete: true}
+				}
+				word.WriteString(string(runes[i : end+1]))
+				i = end
+				continue
+			}
+			word.WriteRune(r)
+			if r == '\\' && i+1 < len(runes) {
+				word.WriteRune(runes[i+1])
+				i++
+			} else if r == '"' {
+				state = stateWord
+			}
+		}
+	}
+	switch state {
+	case stateSingle:
+		return nil, &SyntaxError{Msg: "unterminated quote '", Incomplete: true}
+	case stateDouble:
+		return nil, &SyntaxError{Msg: "unterminated quote \"", Incomplete: true}
+	}
+	endWord()
+	return tokens, nil
+}
+
+// Ищет скобку, закрывающую открытую в позиции open, с учетом вложенности и кавычек
+func matchParen(runes []rune, open int) (int, bool) {
+	depth := 0
+	for i := open; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			i++
+		case '\'':
+			for i++; i < len(runes) && runes[i] != '\''; i++ {
+			}
+		case '"':
+			for i++; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, true
+			}
+		}
+		if i >= len(runes) {
+			break
+		}
+	}
+	return 0, false
+}
+
+// Оператор в начале строки или пустая строка
+func matchOperator(runes []rune) string {
+	for _, op := range operators {
+		if strings.HasPrefix(string(runes[:min(len(runes), len(op))]), op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// Разбивает строку на слова, сохраняя кавычки. Операторы считаются разделителями слов
+func splitWords(input string) ([]string, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	var words []string
+	for _, tok := range tokens {
+		if tok.kind == tokWord {
+			words = append(words, tok.text)
+		}
+	}
+	return words, nil
+}
+
+// Удаляет кавычки и экранирование из слова, полученного от splitWords
+func unquoteWord(raw string) string {
+	var b strings.Builder
+	state := stateWord
+	runes := []rune(raw)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch state {
+		case stateWord:
+			switch r {
+			case '\\':
+				if i+1 < len(runes) {
+					i++
+					b.WriteRune(runes[i])
+				}
+			case '\'':
+				state = stateSingle
+			case '"':
+				state = stateDouble
+			default:
+				b.WriteRune(r)
+			}
+		case stateSingle:
+			if r == '\'' {
+				state = stateWord
+			} else {
+				b.WriteRune(r)
+			}
+		case stateDouble:
+			switch {
+			case r == '"':
+				state = stateWord
+			case r == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]):
+				// внутри двойных кавычек \ экранирует только $ ` " \ и перевод строки
+				i++
+				if runes[i] != '\n' {
+					b.WriteRune(runes[i])
+				}
+			default:
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// Разбивает строку на слова с удалением кавычек
+func tokenize(input string) ([]string, error) {
+	words, err := splitWords(input)
+	if err != nil {
+		return nil, err
+	}
+	for i, word := range words {
+		words[i] = unquoteWord(word)
+	}
+	return words, nil
+}
+
+// Заключает слово в кавычки так, чтобы tokenize вернул его без изменений
+func quoteWord(word string) string {
+	if word == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(word, " \t\n\r'\"\\#$`;&|<>(){}*?[]~") {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
@@ -0,0 +1,494 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Ввод строки прерван по Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// Коды управляющих клавиш
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// Клавиши, передаваемые escape-последовательностями
+const (
+	keyUp = -(iota + 1)
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+// Редактор строки для интерактивного режима: перемещение курсора, просмотр истории
+// стрелками, обратный поиск по Ctrl-R и дополнение по Tab
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	raw      func() (func(), error)            // переводит терминал в посимвольный режим, nil - не нужно
+	history  func() []string                   // записи истории от старых к новым
+	complete func(line string) ([]string, int) // варианты дополнения текста до курсора и начало дополняемого слова
+
+	prompt  string
+	buf     []rune
+	pos     int // позиция курсора в buf
+	cursorY int // строка курсора относительно первой строки ввода на экране
+}
+
+// Редактор строки для терминала или nil, если ввод не является терминалом
+func (s *Shell) newLineEditor(in, out *os.File) *lineEditor {
+	if !isTerminal(in.Fd()) {
+		return nil
+	}
+	return &lineEditor{
+		in:       bufio.NewReader(in),
+		out:      out,
+		raw:      func() (func(), error) { return makeRaw(in.Fd()) },
+		history:  s.history.Entries,
+		complete: s.completions,
+	}
+}
+
+// Считывает строку. При Ctrl-D на пустой строке возвращает io.EOF, при Ctrl-C - errInterrupted
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	if e.raw != nil {
+		restore, err := e.raw()
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+	e.prompt, e.buf, e.pos, e.cursorY = prompt, nil, 0, 0
+	e.refresh()
+	history := e.history()
+	histIndex := len(history) // len(history) - редактируемая строка
+	var saved []rune          // редактируемая строка на время просмотра истории
+	lastTab := false
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			if err == io.EOF && len(e.buf) > 0 {
+				e.finish()
+				return string(e.buf), nil
+			}
+			return "", err
+		}
+		tab := false
+		switch key {
+		case keyEnter, '\n':
+			e.finish()
+			return string(e.buf), nil
+		case keyCtrlC:
+			e.pos = len(e.buf)
+			e.refresh()
+			io.WriteString(e.out, "^C\r\n")
+			e.cursorY = 0
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(e.buf) == 0 {
+				e.finish()
+				return "", io.EOF
+			}
+			e.delete(e.pos, e.pos+1)
+		case keyCtrlA, keyHome:
+			e.pos = 0
+		case keyCtrlE, keyEnd:
+			e.pos = len(e.buf)
+		case keyCtrlB, keyLeft:
+			e.pos = max(e.pos-1, 0)
+		case keyCtrlF, keyRight:
+			e.pos = min(e.pos+1, len(e.buf))
+		case keyBackspace, keyCtrlH:
+			if e.pos > 0 {
+				e.delete(e.pos-1, e.pos)
+			}
+		case keyDelete:
+			e.delete(e.pos, e.pos+1)
+		case keyCtrlK:
+			e.delete(e.pos, len(e.buf))
+		case keyCtrlU:
+			e.delete(0, e.pos)
+		case keyCtrlW:
+			// удаляет слово перед курсором
+			start := e.pos
+			for start > 0 && e.buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && e.buf[start-1] != ' ' {
+				start--
+			}
+			e.delete(start, e.pos)
+		case keyCtrlL:
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+			e.cursorY = 0
+		case keyUp, keyCtrlP, keyDown, keyCtrlN:
+			next := histIndex - 1
+			if key == keyDown || key == keyCtrlN {
+				next = histIndex + 1
+			}
+			if next < 0 || next > len(history) {
+				break
+			}
+			if histIndex == len(history) {
+				saved = e.buf
+			}
+			histIndex = next
+			if histIndex == len(history) {
+				e.buf = saved
+			} else {
+				e.buf = []rune(history[histIndex])
+			}
+			e.pos = len(e.buf)
+		case keyCtrlR:
+			line, action := e.search(history)
+			if action != searchCancel {
+				e.buf, e.pos = line, len(line)
+			}
+			if action == searchExecute {
+				e.finish()
+				return string(e.buf), nil
+			}
+		case keyTab:
+			e.completeWord(lastTab)
+			tab = true
+		default:
+			if key >= ' ' {
+				e.insert(rune(key))
+			}
+		}
+		lastTab = tab
+		e.refresh()
+	}
+}
+
+// Читает одну клавишу, распознавая escape-последовательности стрелок и Home/End/Delete
+func (e *lineEditor) readKey() (int, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if r != keyEscape {
+		return int(r), nil
+	}
+	r, _, err = e.in.ReadRune()
+	if err != nil {
+		return keyEscape, nil
+	}
+	if r != '[' && r != 'O' {
+		return keyUnknown, nil
+	}
+	// Параметры последовательности: ESC [ 1 ; 5 C, ESC [ 3 ~ и т.п.
+	var params []rune
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return keyUnknown, nil
+		}
+		if (r >= '0' && r <= '9') || r == ';' {
+			params = append(params, r)
+			continue
+		}
+		break
+	}
+	switch r {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	case '~':
+		switch string(params) {
+		case "1", "7":
+			return keyHome, nil
+		case "4", "8":
+			return keyEnd, nil
+		case "3":
+			return keyDelete, nil
+		}
+	}
+	return keyUnknown, nil
+}
+
+func (e *lineEditor) insert(runes ...rune) {
+	e.buf = append(e.buf[:e.pos], append(runes, e.buf[e.pos:]...)...)
+	e.pos += len(runes)
+}
+
+func (e *lineEditor) delete(from, to int) {
+	to = min(to, len(e.buf))
+	if from >= to {
+		return
+	}
+	e.buf = append(e.buf[:from:from], e.buf[to:]...)
+	if e.pos > to {
+		e.pos -= to - from
+	} else if e.pos > from {
+		e.pos = from
+	}
+}
+
+// Перерисовывает приглашение и строку. Многострочные команды из истории
+// выводятся с вторичным приглашением на каждой следующей строке
+func (e *lineEditor) refresh() {
+	e.draw(e.prompt, string(e.buf), e.pos)
+}
+
+func (e *lineEditor) draw(prompt, text string, pos int) {
+	var b strings.Builder
+	if e.cursorY > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", e.cursorY)
+	}
+	b.WriteString("\r\x1b[J")
+	// Приглашение тоже может занимать несколько строк
+	promptLines := strings.Split(prompt, "\n")
+	b.WriteString(strings.Join(promptLines, "\r\n"))
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n" + secondaryPrompt)
+		}
+		b.WriteString(line)
+	}
+	// Курсор возвращается с конца текста на позицию pos
+	before := strings.Split(string([]rune(text)[:pos]), "\n")
+	row := len(before) - 1
+	col := displayWidth(before[row])
+	if row == 0 {
+		col += displayWidth(promptLines[len(promptLines)-1])
+	} else {
+		col += displayWidth(secondaryPrompt)
+	}
+	if up := len(lines) - 1 - row; up > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", up)
+	}
+	b.WriteString("\r")
+	if col > 0 {
+		fmt.Fprintf(&b, "\x1b[%dC", col)
+	}
+	e.cursorY = len(promptLines) - 1 + row
+	io.WriteString(e.out, b.String())
+}
+
+// Переводит курсор в конец ввода и на новую строку
+func (e *lineEditor) finish() {
+	e.pos = len(e.buf)
+	e.refresh()
+	io.WriteString(e.out, "\r\n")
+	e.cursorY = 0
+}
+
+// Escape-последовательности терминала, не занимающие места на экране
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]|\x1b\][^\x07]*\x07`)
+
+// Ширина строки на экране без учета escape-последовательностей
+func displayWidth(str string) int {
+	return utf8.RuneCountInString(ansiEscape.ReplaceAllString(str, ""))
+}
+
+// Результат обратного поиска
+const (
+	searchCancel  = iota // строка ввода не меняется
+	searchAccept         // найденная строка становится строкой ввода
+	searchExecute        // найденная строка выполняется
+)
+
+// Обратный поиск по истории (Ctrl-R). Повторное Ctrl-R ищет более раннее совпадение,
+// Enter выполняет найденную строку, прочие управляющие клавиши принимают ее для редактирования,
+// Ctrl-G и Ctrl-C отменяют поиск
+func (e *lineEditor) search(history []string) ([]rune, int) {
+	var query []rune
+	index := len(history)
+	match := ""
+	find := func(from int) {
+		for i := from; i >= 0; i-- {
+			if i < len(history) && strings.Contains(history[i], string(query)) {
+				index, match = i, history[i]
+				return
+			}
+		}
+	}
+	for {
+		label := "(reverse-i-search)`" + string(query) + "': "
+		pos := strings.Index(match, string(query))
+		if pos < 0 || len(query) == 0 {
+			pos = 0
+		}
+		e.draw(label, match, utf8.RuneCountInString(match[:pos]))
+		key, err := e.readKey()
+		if err != nil {
+			return nil, searchCancel
+		}
+		switch key {
+		case keyCtrlR:
+			if len(query) > 0 {
+				find(index - 1)
+			}
+		case keyBackspace, keyCtrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				index, match = len(history), ""
+				if len(query) > 0 {
+					find(len(history) - 1)
+				}
+			}
+		case keyCtrlG, keyCtrlC:
+			return nil, searchCancel
+		case keyEnter, '\n':
+			return []rune(match), searchExecute
+		default:
+			if key >= ' ' {
+				query = append(query, rune(key))
+				find(index)
+				if !strings.Contains(match, string(query)) {
+					match = ""
+				}
+				continue
+			}
+			return []rune(match), searchAccept
+		}
+	}
+}
+
+// Дополняет слово перед курсором. Если вариантов несколько и общий префикс
+// уже введен, повторное нажатие Tab выводит список вариантов
+func (e *lineEditor) completeWord(repeated bool) {
+	if e.complete == nil {
+		return
+	}
+	candidates, start := e.complete(string(e.buf[:e.pos]))
+	if len(candidates) == 0 {
+		return
+	}
+	word := string(e.buf[start:e.pos])
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	if len(candidates) == 1 && !strings.HasSuffix(prefix, "/") {
+		prefix += " "
+	}
+	if prefix != word && strings.HasPrefix(prefix, word) {
+		e.insert([]rune(prefix[len(word):])...)
+		return
+	}
+	if len(candidates) > 1 && repeated {
+		e.pos = len(e.buf)
+		e.refresh()
+		io.WriteString(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+		e.cursorY = 0
+	}
+}
+
+// Варианты дополнения для текста line перед курсором: имена команд, функций и псевдонимов
+// в начале команды, пути VFS в остальных словах. Возвращает варианты
+// (с экранированными специальными символами) и позицию начала дополняемого слова
+func (s *Shell) completions(line string) ([]string, int) {
+	runes := []rune(line)
+	start := len(runes)
+	for start > 0 {
+		// разделитель, экранированный \, входит в слово
+		if strings.ContainsRune(" \t;&|()", runes[start-1]) && (start < 2 || runes[start-2] != '\\') {
+			break
+		}
+		start--
+	}
+	word := unquoteWord(string(runes[start:]))
+	before := strings.TrimRight(string(runes[:start]), " \t")
+	commandPosition := before == "" || strings.ContainsRune(";&|(", rune(before[len(before)-1]))
+
+	var candidates []string
+	if commandPosition && !strings.Contains(word, "/") {
+		seen := map[string]bool{}
+		for _, names := range [][]string{s.commandOrder, keys(s.functions), keys(s.aliases)} {
+			for _, name := range names {
+				if strings.HasPrefix(name, word) && !seen[name] {
+					seen[name] = true
+					candidates = append(candidates, escapeCompletion(name))
+				}
+			}
+		}
+		sort.Strings(candidates)
+		return candidates, start
+	}
+
+	dir, base := path.Split(word)
+	node, err := s.vfs.FindNode(s.absPath(dir))
+	if err != nil || !node.IsDir {
+		return nil, start
+	}
+	for _, child := range node.Children {
+		if !strings.HasPrefix(child.Name, base) || (strings.HasPrefix(child.Name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		name := dir + child.Name
+		if child.IsDir {
+			name += "/"
+		}
+		candidates = append(candidates, escapeCompletion(name))
+	}
+	sort.Strings(candidates)
+	return candidates, start
+}
+
+// Экранирует специальные символы в дополненном слове
+func escapeCompletion(word string) string {
+	var b strings.Builder
+	for _, r := range word {
+		if strings.ContainsRune(" \t'\"\\$&;|()<>*?[]#`", r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Ключи отображения в порядке сортировки
+func keys[V any](m map[string]V) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -5,9 +5,9 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"os/user"
-	"regexp"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -15,16 +15,40 @@ import (
 	"github.com/TimofeyChernyshev/MIREA-Configuration-management-1/vfs"
 )
 
-// Структура для хранения команд - карта, где ключи - имена команд, а значения - функции
+// Структура для хранения команд - карта, где ключи - имена команд, а значения - реализации Command
 type Shell struct {
-	commands    map[string]func([]string)
-	vfs         vfs.VFS
-	currentPath string
+	commands      map[string]Command
+	commandOrder  []string // порядок регистрации команд
+	vfs           *vfs.VFS
+	currentPath   string
+	vars          map[string]string      // переменные оболочки
+	status        int                    // статус завершения последней команды ($?)
+	lastStatus    int                    // статус команды, предшествовавшей текущей
+	flow          *flowControl           // отложенный break/continue/return/exit
+	loopDepth     int                    // вложенность выполняемых циклов
+	aliases       map[string]string      // псевдонимы команд
+	activeAliases map[string]bool        // раскрываемые в данный момент псевдонимы
+	functions     map[string]commandNode // функции, определенные пользователем
+	params        []string               // позиционные параметры $1..$9
+	callDepth     int                    // вложенность вызовов функций и выполняемых скриптов
+	subshell      bool                   // дочерний контекст (sh): exit завершает только его
+	stdin         io.Reader              // перенаправленный ввод, nil - os.Stdin
+	stdout        io.Writer              // перенаправленный вывод, nil - os.Stdout
+	stderr        io.Writer              // вывод ошибок, nil - os.Stdout
+	expandErr     error                  // ошибка при раскрытии слов текущей команды
+	substStatus   int                    // статус последней подстановки $(...) в текущей команде
+	history       *History               // история команд интерактивного режима
+	outputFormat  string                 // формат вывода результатов: text или json
+	record        *commandResult         // результат выполняемой команды в режиме JSON
+	jobs          *jobControl            // фоновые задания, общие с дочерними контекстами
+	locked        bool                   // контекст удерживает блокировку выполнения jobs.mu
+	background    bool                   // контекст выполняет фоновое задание
+	lastJob       int                    // номер последнего запущенного фонового задания ($!)
 }
 
 func NewShell() *Shell {
 	shell := &Shell{}
-	shell.vfs = vfs.VFS{
+	shell.vfs = &vfs.VFS{
 		Root: &vfs.VFSNode{
 			Name:     "/",
 			IsDir:    true,
@@ -34,48 +58,421 @@ func NewShell() *Shell {
 		IsLoaded: false,
 	}
 	shell.currentPath = "/"
-	shell.commands = map[string]func([]string){
-		"ls":       shell.lsCommand,
-		"cd":       shell.cdCommand,
-		"exit":     shell.exitCommand,
-		"vfs-save": shell.vfsSaveCommand,
-		"uniq":     shell.uniqCommand,
-		"tail":     shell.tailCommand,
-		"mv":       shell.mvCommand,
-		"chown":    shell.chownCommand,
-	}
+	shell.vars = map[string]string{}
+	shell.aliases = map[string]string{}
+	shell.activeAliases = map[string]bool{}
+	shell.functions = map[string]commandNode{}
+	shell.history = NewHistory(defaultHistorySize)
+	shell.vars["PS1"] = defaultPrompt
+	shell.outputFormat = outputText
+	shell.jobs = &jobControl{}
+	shell.commands = map[string]Command{}
+	shell.registerFunc(CommandInfo{
+		Name:  "ls",
+		Usage: "ls [PATH...]",
+		Short: "list directory contents",
+		Long:  "Lists the files and directories in each PATH, or in the current directory if PATH is omitted.",
+	}, shell.lsCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "cd",
+		Usage: "cd [PATH]",
+		Short: "change the current directory",
+		Long:  "Changes the current directory to PATH. Without arguments changes to the root directory.",
+	}, shell.cdCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "exit",
+		Usage: "exit [N]",
+		Short: "exit the shell",
+		Long:  "Exits the shell with status N, or with the status of the last command if N is omitted.",
+	}, shell.exitCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "vfs-save",
+		Usage: "vfs-save PATH",
+		Short: "save the VFS to disk",
+		Long:  "Writes the whole in-memory VFS into the directory PATH on the host disk.",
+	}, shell.vfsSaveCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "uniq",
+		Usage: "uniq [-cdui] [-f N] [-s N] [INPUT [OUTPUT]]",
+		Short: "report or omit repeated lines",
+		Long:  "Filters adjacent matching lines from INPUT (standard input if omitted or '-'), writing to OUTPUT or standard output. Repeated lines that are not adjacent are not detected, so the input is usually sorted first: sort FILE | uniq -c.",
+		Flags: []FlagSpec{
+			{Short: "c", Long: "count", Help: "prefix lines by the number of occurrences"},
+			{Short: "d", Long: "repeated", Help: "only print duplicate lines, one for each group"},
+			{Short: "u", Long: "unique", Help: "only print unique lines"},
+			{Short: "i", Long: "ignore-case", Help: "ignore differences in case when comparing"},
+			{Short: "f", Long: "skip-fields", Arg: "N", Help: "avoid comparing the first N fields"},
+			{Short: "s", Long: "skip-chars", Arg: "N", Help: "avoid comparing the first N characters"},
+		},
+	}, shell.uniqCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "sort",
+		Usage: "sort [-nru] [-k KEYDEF] [-t SEP] [-o FILE] [FILE...]",
+		Short: "sort lines of text",
+		Long:  "Writes the sorted concatenation of all FILEs (standard input if none or '-'). KEYDEF is START[,END] with field numbers starting at 1, optionally followed by 'n' or 'r'; lines with equal keys are compared as a whole.",
+		Flags: []FlagSpec{
+			{Short: "n", Long: "numeric-sort", Help: "compare according to string numerical value"},
+			{Short: "r", Long: "reverse", Help: "reverse the result of comparisons"},
+			{Short: "u", Long: "unique", Help: "output only the first of lines with equal keys"},
+			{Short: "k", Long: "key", Arg: "KEYDEF", Help: "sort via a key; may be repeated"},
+			{Short: "t", Long: "field-separator", Arg: "SEP", Help: "use SEP instead of blank-to-non-blank transition"},
+			{Short: "o", Long: "output", Arg: "FILE", Help: "write result to FILE instead of standard output"},
+		},
+	}, shell.sortCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "tail",
+		Usage: "tail [-fqv] [-c N] [-n N] [FILE...]",
+		Short: "output the last part of files",
+		Long:  "Prints the last 10 lines of each FILE (standard input if none or '-'). With more than one FILE precedes each with a header giving the file name. With -f keeps printing data appended to the files until interrupted with Ctrl-C; the data can be appended by background jobs started with '&' (for example, '{ sleep 1; echo line | tee -a log; } & tail -f log') or by a program embedding the shell.",
+		Flags: []FlagSpec{
+			{Short: "c", Long: "bytes", Arg: "N", Help: "output the last N bytes; or use -c +N to output starting with byte N"},
+			{Short: "n", Long: "lines", Arg: "N", Help: "output the last N lines instead of the last 10; or use -n +N to output starting with line N"},
+			{Short: "f", Long: "follow", Help: "output appended data as the files grow"},
+			{Short: "q", Long: "quiet", Help: "never output headers giving file names"},
+			{Short: "v", Long: "verbose", Help: "always output headers giving file names"},
+		},
+	}, shell.tailCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "mv",
+		Usage: "mv SOURCE... DEST",
+		Short: "move or rename files",
+		Long:  "Renames SOURCE to DEST, or moves SOURCE(s) into the directory DEST.",
+	}, shell.mvCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "chown",
+		Usage: "chown OWNER FILE...",
+		Short: "change file owner",
+	}, shell.chownCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "help",
+		Usage: "help [COMMAND...]",
+		Short: "display information about commands",
+		Long:  "Without arguments lists all commands. With COMMAND prints its usage, description and options.",
+	}, shell.helpCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "man",
+		Usage: "man COMMAND...",
+		Short: "display the manual page of a command",
+	}, shell.manCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "echo",
+		Usage: "echo [-n] [STRING...]",
+		Short: "display a line of text",
+		Flags: []FlagSpec{
+			{Short: "n", Help: "do not output the trailing newline"},
+		},
+		NoHelpFlag: true,
+	}, shell.echoCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "test",
+		Usage: "test EXPRESSION",
+		Short: "evaluate conditional expression",
+		Long: "Exits with status 0 if EXPRESSION is true and 1 otherwise. File tests: -e, -f, -d, -s FILE; " +
+			"strings: -z, -n STRING, S1 = S2, S1 != S2; integers: N1 -eq|-ne|-lt|-le|-gt|-ge N2; " +
+			"files: F1 -nt|-ot F2. Expressions are combined with !, -a, -o and parentheses.",
+		NoHelpFlag: true,
+	}, shell.testCommand)
+	shell.registerFunc(CommandInfo{
+		Name:       "[",
+		Usage:      "[ EXPRESSION ]",
+		Short:      "evaluate conditional expression",
+		Long:       "Same as test, the last argument must be ']'.",
+		NoHelpFlag: true,
+	}, shell.bracketCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "true",
+		Usage: "true",
+		Short: "do nothing, successfully",
+	}, func([]string) {})
+	shell.registerFunc(CommandInfo{
+		Name:  "false",
+		Usage: "false",
+		Short: "do nothing, unsuccessfully",
+	}, func([]string) { shell.status = 1 })
+	shell.registerFunc(CommandInfo{
+		Name:  "break",
+		Usage: "break [N]",
+		Short: "exit from N enclosing loops",
+	}, shell.breakCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "continue",
+		Usage: "continue [N]",
+		Short: "resume the next iteration of the N-th enclosing loop",
+	}, shell.continueCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "unset",
+		Usage: "unset [-fv] NAME...",
+		Short: "unset shell variables and functions",
+		Flags: []FlagSpec{
+			{Short: "f", Help: "treat each NAME as a shell function"},
+			{Short: "v", Help: "treat each NAME as a shell variable"},
+		},
+	}, shell.unsetCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "alias",
+		Usage: "alias [NAME[=VALUE]...]",
+		Short: "define or display aliases",
+		Long:  "Without arguments prints all aliases. NAME=VALUE defines an alias that is expanded before looking up functions and commands.",
+	}, shell.aliasCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "unalias",
+		Usage: "unalias [-a] NAME...",
+		Short: "remove aliases",
+		Flags: []FlagSpec{
+			{Short: "a", Help: "remove all aliases"},
+		},
+	}, shell.unaliasCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "return",
+		Usage: "return [N]",
+		Short: "return from a shell function",
+		Long:  "Returns from a function with status N, or with the status of the last command if N is omitted.",
+	}, shell.returnCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "shift",
+		Usage: "shift [N]",
+		Short: "shift positional parameters",
+	}, shell.shiftCommand)
+	shell.registerFunc(CommandInfo{
+		Name:          "source",
+		Usage:         "source FILE [ARG...]",
+		Short:         "execute commands from a file in the current shell",
+		Long:          "Reads FILE from the VFS or, if it is not found there, from the host disk and executes it in the current shell context. ARGs become positional parameters.",
+		StopAtOperand: true,
+	}, shell.sourceCommand)
+	shell.registerFunc(CommandInfo{
+		Name:          ".",
+		Usage:         ". FILE [ARG...]",
+		Short:         "execute commands from a file in the current shell",
+		Long:          "Same as source.",
+		StopAtOperand: true,
+	}, shell.sourceCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "sh",
+		Usage: "sh [-c COMMAND | FILE] [ARG...]",
+		Short: "run a script in a child shell",
+		Long:  "Executes FILE (from the VFS or the host disk) or COMMAND in a child context: changes of the current directory, variables, functions and aliases do not affect the calling shell.",
+		Flags: []FlagSpec{
+			{Short: "c", Help: "read commands from the first operand instead of a file"},
+		},
+		StopAtOperand: true,
+	}, shell.shCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "wait",
+		Usage: "wait [ID...]",
+		Short: "wait for background jobs",
+		Long:  "Waits for the background jobs with the given IDs (as in $!, optionally prefixed with %) and returns the exit status of the last one. Without IDs waits for all jobs and returns 0. While waiting, the jobs run; Ctrl-C stops waiting with status 130.",
+	}, shell.waitCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "sleep",
+		Usage: "sleep NUMBER[SUFFIX]...",
+		Short: "delay for a specified amount of time",
+		Long:  "Pauses for the sum of the given intervals. NUMBER may be fractional; SUFFIX is s for seconds (the default), m for minutes, h for hours or d for days. Background jobs keep running meanwhile.",
+	}, shell.sleepCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "wc",
+		Usage: "wc [-lwc] [FILE...]",
+		Short: "print newline, word and byte counts",
+		Long:  "Prints the number of lines, words and bytes in each FILE, or in the standard input if FILE is omitted. With more than one FILE also prints a total line.",
+		Flags: []FlagSpec{
+			{Short: "l", Long: "lines", Help: "print the newline counts"},
+			{Short: "w", Long: "words", Help: "print the word counts"},
+			{Short: "c", Long: "bytes", Help: "print the byte counts"},
+		},
+	}, shell.wcCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "history",
+		Usage: "history [-c] [N]",
+		Short: "display the command history",
+		Long:  "Prints the command history with entry numbers, or only the last N entries. Entries can be recalled with !!, !N, !-N and !PREFIX.",
+		Flags: []FlagSpec{
+			{Short: "c", Help: "clear the history"},
+		},
+	}, shell.historyCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "stat",
+		Usage: "stat [-c FORMAT] FILE...",
+		Short: "display file status",
+		Long:  "Prints the size, type, permissions, owner, group, inode number and access, modification, change and birth times of each FILE.",
+		Flags: []FlagSpec{
+			{Short: "c", Long: "format", Arg: "FORMAT", Help: "use FORMAT instead of the default: %n name, %s size, %F type, %a/%A permissions, %U/%G owner and group, %i inode, %h links, %x/%y/%z/%w times, %X/%Y/%Z/%W times in seconds"},
+		},
+	}, shell.statCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "touch",
+		Usage: "touch [-acm] [-d DATE | -r FILE] FILE...",
+		Short: "change file timestamps",
+		Long:  "Sets the access and modification times of each FILE to the current time, creating empty files that do not exist.",
+		Flags: []FlagSpec{
+			{Short: "a", Help: "change only the access time"},
+			{Short: "m", Help: "change only the modification time"},
+			{Short: "c", Long: "no-create", Help: "do not create any files"},
+			{Short: "d", Long: "date", Arg: "DATE", Help: "use DATE (YYYY-MM-DD[ HH:MM[:SS]], RFC 3339 or @SECONDS) instead of the current time"},
+			{Short: "r", Long: "reference", Arg: "FILE", Help: "use the times of FILE instead of the current time"},
+		},
+	}, shell.touchCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "find",
+		Usage: "find [PATH...] [EXPRESSION]",
+		Short: "search for files in a directory hierarchy",
+		Long: "Walks each PATH (the current directory by default) and evaluates EXPRESSION for every file, printing matching paths if EXPRESSION has no actions. " +
+			"Tests: -name PATTERN, -iname PATTERN, -type f|d, -size [+-]N[cwbkMG], -mtime [+-]N, -user NAME, -newer FILE, -true, -false; " +
+			"options: -maxdepth N, -mindepth N, -depth; actions: -print, -print0, -delete, -exec COMMAND {} ';'. " +
+			"Expressions are combined with !, -a, -o and parentheses.",
+	}, shell.findCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "tree",
+		Usage: "tree [-adhsu] [-L LEVEL] [PATH...]",
+		Short: "list contents of directories in a tree-like format",
+		Long:  "Prints each PATH (the current directory by default) and its contents as a tree sorted by name, followed by the number of directories and files. Entries starting with '.' are hidden unless -a is given.",
+		Flags: []FlagSpec{
+			{Short: "a", Help: "list all files, including hidden ones"},
+			{Short: "d", Help: "list directories only"},
+			{Short: "L", Arg: "LEVEL", Help: "descend only LEVEL directories deep"},
+			{Short: "s", Help: "print the size of each file in bytes"},
+			{Short: "h", Help: "print sizes in a human readable format"},
+			{Short: "u", Help: "print the owner of each file"},
+		},
+	}, shell.treeCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "du",
+		Usage: "du [-abhs] [-d N] [PATH...]",
+		Short: "estimate file space usage",
+		Long:  "Prints the total size of file contents in each directory under PATH (the current directory by default), in kilobytes rounded up.",
+		Flags: []FlagSpec{
+			{Short: "a", Long: "all", Help: "write counts for all files, not just directories"},
+			{Short: "b", Long: "bytes", Help: "print sizes in bytes"},
+			{Short: "h", Long: "human-readable", Help: "print sizes in a human readable format (e.g., 1K 234M 2G)"},
+			{Short: "s", Long: "summarize", Help: "display only a total for each argument"},
+			{Short: "d", Long: "max-depth", Arg: "N", Help: "print the total for a directory only if it is N or fewer levels below PATH"},
+		},
+	}, shell.duCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "df",
+		Usage: "df [-hi]",
+		Short: "report VFS space usage",
+		Long:  "Prints the total size of file contents and the number of nodes in the VFS against the limits set by -quota (256M by default) and -max-nodes.",
+		Flags: []FlagSpec{
+			{Short: "h", Long: "human-readable", Help: "print sizes in a human readable format (e.g., 1K 234M 2G)"},
+			{Short: "i", Long: "inodes", Help: "list node usage instead of byte usage"},
+		},
+	}, shell.dfCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "quota",
+		Usage: "quota [-h] [OWNER...]",
+		Short: "display per-owner disk usage and limits",
+		Long:  "Prints the total size of files of each OWNER (all owners by default), their quota set by -user-quota and the number of files. Usage over the quota is marked with '*'.",
+		Flags: []FlagSpec{
+			{Short: "h", Long: "human-readable", Help: "print sizes in a human readable format (e.g., 1K 234M 2G)"},
+		},
+	}, shell.quotaCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "sed",
+		Usage: "sed [-nEi] [-e SCRIPT]... [SCRIPT] [FILE...]",
+		Short: "stream editor for filtering and transforming text",
+		Long: "Applies SCRIPT to each line of the FILEs (standard input if none or '-') and prints the result. " +
+			"Commands are separated by ';' or newlines and may be preceded by an address (N, $, /RE/) or a range ADDR1,ADDR2, optionally negated with '!': " +
+			"s/RE/REPLACEMENT/[gpiN] substitutes ('&' is the match, \\1-\\9 are groups), d deletes the line, p prints it, = prints the line number, q quits.",
+		Flags: []FlagSpec{
+			{Short: "n", Long: "quiet", Help: "suppress automatic printing of each line"},
+			{Short: "e", Long: "expression", Arg: "SCRIPT", Help: "add SCRIPT to the commands to be executed"},
+			{Short: "E", Long: "regexp-extended", Help: "use extended regular expressions in the script"},
+			{Short: "i", Long: "in-place", Help: "edit VFS files in place instead of printing them"},
+		},
+	}, shell.sedCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "cut",
+		Usage: "cut -f LIST [-d DELIM] [-s] [FILE...] | cut -c LIST [FILE...]",
+		Short: "remove sections from each line of files",
+		Long:  "Prints the selected fields or characters of each line of the FILEs (standard input if none or '-'). LIST is made up of N, N-M, N- and -M ranges separated by commas, counted from 1.",
+		Flags: []FlagSpec{
+			{Short: "f", Long: "fields", Arg: "LIST", Help: "select only these fields; lines without the delimiter are printed whole"},
+			{Short: "c", Long: "characters", Arg: "LIST", Help: "select only these characters"},
+			{Short: "d", Long: "delimiter", Arg: "DELIM", Help: "use DELIM instead of TAB for field delimiter"},
+			{Short: "s", Long: "only-delimited", Help: "do not print lines not containing delimiters"},
+		},
+	}, shell.cutCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "tr",
+		Usage: "tr [-ds] SET1 [SET2]",
+		Short: "translate or delete characters",
+		Long:  "Translates characters of standard input from SET1 to SET2 and prints the result. Sets may contain ranges (a-z), classes ([:upper:], [:digit:], [:space:], ...) and escapes (\\n, \\t); a shorter SET2 is padded with its last character.",
+		Flags: []FlagSpec{
+			{Short: "d", Long: "delete", Help: "delete characters in SET1, do not translate"},
+			{Short: "s", Long: "squeeze-repeats", Help: "replace each sequence of a repeated character from the last given set with a single occurrence"},
+		},
+	}, shell.trCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "tee",
+		Usage: "tee [-a] [FILE...]",
+		Short: "copy standard input to standard output and files",
+		Long:  "Copies standard input to standard output and to each FILE in the VFS, line by line, creating missing files.",
+		Flags: []FlagSpec{
+			{Short: "a", Long: "append", Help: "append to the given FILEs, do not overwrite"},
+		},
+	}, shell.teeCommand)
+	shell.registerFunc(CommandInfo{
+		Name:  "awk",
+		Usage: "awk [-F FS] [-v VAR=VALUE]... PROGRAM [FILE...]",
+		Short: "pattern scanning and processing language",
+		Long: "Runs PROGRAM for each line of the FILEs (standard input if none or '-'), reading them line by line. PROGRAM consists of 'PATTERN { ACTION }' rules, where PATTERN is BEGIN, END, /RE/ or an expression " +
+			"and ACTION is a list of print and printf statements. Expressions support fields $0..$NF, variables NR, NF, FNR, FS, OFS, ORS, FILENAME and those set with -v, " +
+			"arithmetic, comparison, &&, ||, !, ~ and !~ matching and string concatenation. Assignment, control flow and functions are not supported.",
+		Flags: []FlagSpec{
+			{Short: "F", Long: "field-separator", Arg: "FS", Help: "use FS for the input field separator"},
+			{Short: "v", Long: "assign", Arg: "VAR=VALUE", Help: "assign VALUE to the variable VAR before the program starts"},
+		},
+	}, shell.awkCommand)
 	return shell
 }
 
 // SHELL METHODS
 func (s *Shell) lsCommand(args []string) {
 	// Выводит список файлов в директории
-	var path string
-	if len(args) > 0 {
-		path = args[0]
-	} else {
-		path = s.currentPath
-	}
-	node, err := s.vfs.FindNode(path)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+	_, paths, ok := s.parseArgs("ls", args)
+	if !ok {
 		return
 	}
-	if !node.IsDir {
-		fmt.Printf("Error: %s is not a directory\n", path)
-		return
+	if len(paths) == 0 {
+		paths = []string{s.currentPath}
 	}
-	for _, child := range node.Children {
-		fmt.Printf("%s\n", child.Name)
+	for i, path := range paths {
+		node, err := s.vfs.FindNode(path)
+		if err != nil {
+			s.pathError("ls", path, err)
+			continue
+		}
+		if !node.IsDir {
+			s.pathError("ls", path, vfs.ErrNotDir)
+			continue
+		}
+		// Заголовок для нескольких директорий
+		if len(paths) > 1 && !s.recording() {
+			if i > 0 {
+				fmt.Fprintln(s.out())
+			}
+			fmt.Fprintf(s.out(), "%s:\n", path)
+		}
+		for _, child := range node.Children {
+			if s.emit(lsEntry{Path: path, Name: child.Name, Type: nodeType(child.IsDir), Size: len(child.Content), Owner: child.Owner, ModTime: child.ModTime}) {
+				continue
+			}
+			fmt.Fprintf(s.out(), "%s\n", child.Name)
+		}
 	}
 }
+
 func (s *Shell) cdCommand(args []string) {
 	// Позволяет установить текущую директорию
-	if len(args) == 0 {
+	_, operands, ok := s.parseArgs("cd", args)
+	if !ok {
+		return
+	}
+	if len(operands) == 0 {
 		s.currentPath = "/"
 		return
 	}
-	path := args[0]
+	path := operands[0]
 	var targetPath string
 	if path == "/" {
 		targetPath = "/"
@@ -104,140 +501,136 @@ func (s *Shell) cdCommand(args []string) {
 	}
 	node, err := s.vfs.FindNode(targetPath)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		s.pathError("cd", path, err)
 		return
 	}
 	if !node.IsDir {
-		fmt.Printf("Error: %v is not a directory\n", targetPath)
+		s.pathError("cd", path, vfs.ErrNotDir)
 		return
 	}
 	s.currentPath = targetPath
+	s.emit(cdResult{Path: targetPath})
 }
 func (s *Shell) exitCommand(args []string) {
-	os.Exit(0)
+	_, operands, ok := s.parseArgs("exit", args)
+	if !ok {
+		return
+	}
+	status := s.lastStatus
+	if len(operands) > 0 {
+		n, err := strconv.Atoi(operands[0])
+		if err != nil {
+			s.errorf("exit: %s: numeric argument required\n", operands[0])
+			n = 2
+		}
+		status = n
+	}
+	// В дочернем контексте exit завершает только выполняемый скрипт
+	if s.subshell {
+		s.status = status
+		s.flow = &flowControl{kind: flowExit}
+		return
+	}
+	os.Exit(status)
 }
 func (s *Shell) vfsSaveCommand(args []string) {
 	if len(args) == 0 {
-		fmt.Println("vfs-save: need path to save")
+		s.errorf("vfs-save: need path to save\n")
 		return
 	}
 	if !s.vfs.IsLoaded {
-		fmt.Println("vfs-save: VFS isn`t loaded")
+		s.errorf("vfs-save: VFS isn`t loaded\n")
 		return
 	}
 	err := s.vfs.SaveToDisk(args[0])
 	if err != nil {
-		fmt.Printf("vfs-save: save error: %v\n", err)
-	}
-	fmt.Printf("VFS saved to %v\n", args[0])
-}
-func (s *Shell) uniqCommand(args []string) {
-	// Вывод содержимое файла без повторяющихся строк
-	if len(args) == 0 {
-		fmt.Println("Error: missing arguments")
+		s.pathError("vfs-save", args[0], err)
 		return
 	}
-	filePath := args[0]
-	if !strings.HasPrefix(filePath, "/") {
-		filePath = s.currentPath + "/" + filePath
+	if !s.emit(saveResult{Path: args[0]}) {
+		fmt.Fprintf(s.out(), "VFS saved to %v\n", args[0])
 	}
-	node, err := s.vfs.FindNode(filePath)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		return
-	}
-	if node.IsDir {
-		fmt.Printf("Error: %v is directory", filePath)
+}
+func (s *Shell) wcCommand(args []string) {
+	// Считает строки, слова и байты в файлах или во входном потоке
+	opts, files, ok := s.parseArgs("wc", args)
+	if !ok {
 		return
 	}
-	lines := strings.Split(node.Content, "\n")
-	seen := make(map[string]bool)
-	var result []string
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		if !seen[line] {
-			seen[line] = true
-			result = append(result, line)
+	// Без флагов выводятся все три счетчика
+	selected := 0
+	for _, flag := range []string{"l", "w", "c"} {
+		if opts.Has(flag) {
+			selected++
 		}
 	}
-	for _, line := range result {
-		fmt.Println(line)
+	all := selected == 0
+	// Один счетчик выводится без выравнивания, как в GNU wc
+	format := "%7d"
+	if selected == 1 {
+		format = "%d"
 	}
-}
-func (s *Shell) tailCommand(args []string) {
-	// Выводит последние N строк файла (по умолчанию 10)
-	if len(args) == 0 {
-		fmt.Println("Error: missing arguments")
-		return
-	}
-	lines := 10
-	files := []string{}
-	i := 0
-	for i < len(args) {
-		arg := args[i]
-		if arg == "-n" && i+1 < len(args) {
-			n, err := strconv.Atoi(args[i+1])
-			if err != nil || n <= 0 {
-				fmt.Println("Error: invalid number of lines")
-				return
+	report := func(counts [3]int, name string) {
+		if s.emit(wcResult{File: name, Lines: counts[0], Words: counts[1], Bytes: counts[2]}) {
+			return
+		}
+		var fields []string
+		for i, flag := range []string{"l", "w", "c"} {
+			if all || opts.Has(flag) {
+				fields = append(fields, fmt.Sprintf(format, counts[i]))
 			}
-			lines = n
-			i += 2
-		} else {
-			files = append(files, arg)
-			i++
 		}
+		if name != "" {
+			fields = append(fields, name)
+		}
+		fmt.Fprintln(s.out(), strings.Join(fields, " "))
+	}
+	count := func(content string) [3]int {
+		return [3]int{strings.Count(content, "\n"), len(strings.Fields(content)), len(content)}
 	}
 	if len(files) == 0 {
-		fmt.Println("Error: missing argument")
+		data, err := io.ReadAll(s.in())
+		if err != nil {
+			s.errorf("wc: %v\n", err)
+			return
+		}
+		report(count(string(data)), "")
 		return
 	}
-	for _, fileArg := range files {
-		filePath := fileArg
-		if !strings.HasPrefix(filePath, "/") {
-			filePath = s.currentPath + "/" + filePath
-		}
-		node, err := s.vfs.FindNode(filePath)
+	var total [3]int
+	for _, file := range files {
+		node, err := s.vfs.FindNode(s.absPath(file))
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			s.pathError("wc", file, err)
 			continue
 		}
 		if node.IsDir {
-			fmt.Printf("Error: %s is directory\n", filePath)
-			continue
-		}
-		// Вывод заголовка для нескольких файлов
-		if len(files) > 1 {
-			fmt.Printf("Title: %s\n", fileArg)
-		}
-		contentLines := strings.Split(node.Content, "\n")
-		if len(contentLines) == 0 {
+			s.pathError("wc", file, vfs.ErrIsDir)
 			continue
 		}
-		// Выводим последние N строк
-		start := len(contentLines) - lines
-		if start < 0 {
-			start = 0
-		}
-		for i := start; i < len(contentLines); i++ {
-			fmt.Println(contentLines[i])
-		}
-		if len(files) > 1 && fileArg != files[len(files)-1] {
-			fmt.Println() // Пустая строка между файлами
+		counts := count(node.Content)
+		for i := range total {
+			total[i] += counts[i]
 		}
+		report(counts, file)
+	}
+	if len(files) > 1 {
+		report(total, "total")
 	}
 }
 func (s *Shell) mvCommand(args []string) {
 	// Перемещает/переименовывает файлы и директории
-	if len(args) < 2 {
-		fmt.Println("Error: missing arguments")
+	_, operands, ok := s.parseArgs("mv", args)
+	if !ok {
+		return
+	}
+	if len(operands) < 2 {
+		s.errorf("mv: missing operand\n")
 		return
 	}
 
-	sources := args[:len(args)-1]
-	destination := args[len(args)-1]
+	sources := operands[:len(operands)-1]
+	destination := operands[len(operands)-1]
 
 	// Проверяем, является ли назначение директорией
 	destNode, err := s.vfs.FindNode(destination)
@@ -245,7 +638,7 @@ func (s *Shell) mvCommand(args []string) {
 
 	// Если перемещаем несколько файлов, назначение должно быть директорией
 	if len(sources) > 1 && !isDestDir {
-		fmt.Printf("Error: %s is not a directory\n", destination)
+		s.pathError("mv", destination, vfs.ErrNotDir)
 		return
 	}
 	for _, source := range sources {
@@ -255,7 +648,7 @@ func (s *Shell) mvCommand(args []string) {
 		}
 		sourceNode, err := s.vfs.FindNode(sourcePath)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			s.pathError("mv", source, err)
 			continue
 		}
 		var destPath string
@@ -271,7 +664,7 @@ func (s *Shell) mvCommand(args []string) {
 		}
 		// Проверяем, не пытаемся ли переместить в самого себя
 		if sourcePath == destPath {
-			fmt.Println("Error: trying to move to itself")
+			s.errorf("mv: %s: cannot move a file to itself\n", source)
 			continue
 		}
 		// Проверяем, существует ли уже целевой путь
@@ -281,32 +674,36 @@ func (s *Shell) mvCommand(args []string) {
 			if existingNode.IsDir && sourceNode.IsDir {
 				destPath = destPath + "/" + sourceNode.Name
 			} else {
-				fmt.Printf("Error: cannot move %s to %s; File exists\n", source, destination)
+				s.pathError("mv", destination, vfs.ErrExist)
 				continue
 			}
 		}
 		// Проверяем, не пытаемся ли переместить родительскую папку в дочернюю
 		if strings.HasPrefix(destPath, sourcePath+"/") {
-			fmt.Printf("Error: cannot move %s into its subdirectory %s\n", source, destination)
+			s.errorf("mv: %s: cannot move a directory into its own subdirectory %s\n", source, destination)
 			continue
 		}
 		err = s.vfs.MoveNode(sourcePath, destPath)
 		if err != nil {
-			fmt.Printf("Error: %s\n", err)
-		} else {
-			fmt.Printf("Moved %s to %s\n", source, destination)
+			s.pathError("mv", source, err)
+		} else if !s.emit(mvResult{Source: sourcePath, Destination: destPath}) {
+			fmt.Fprintf(s.out(), "Moved %s to %s\n", source, destination)
 		}
 	}
 }
 func (s *Shell) chownCommand(args []string) {
 	// меняет владельца файла
-	if len(args) < 2 {
-		fmt.Println("Error: missing argument")
+	_, operands, ok := s.parseArgs("chown", args)
+	if !ok {
+		return
+	}
+	if len(operands) < 2 {
+		s.errorf("chown: missing operand\n")
 		return
 	}
 
-	owner := args[0]
-	files := args[1:]
+	owner := operands[0]
+	files := operands[1:]
 
 	for _, file := range files {
 		filePath := file
@@ -314,158 +711,297 @@ func (s *Shell) chownCommand(args []string) {
 			filePath = s.currentPath + "/" + filePath
 		}
 
-		node, err := s.vfs.FindNode(filePath)
-		if err != nil {
-			fmt.Printf("chown: %v\n", err)
+		// Изменяем владельца
+		if err := s.vfs.Chown(filePath, owner); err != nil {
+			s.pathError("chown", file, err)
 			continue
 		}
+		if !s.emit(chownResult{File: filePath, Owner: owner}) {
+			fmt.Fprintf(s.out(), "Changed owner of '%s' to '%s'\n", file, owner)
+			fmt.Fprintf(s.out(), "Owner of file is %s\n", owner) // Выводит текущего владельца файла
+		}
+	}
+}
 
-		// Изменяем владельца
-		node.Owner = owner
-		node.ModTime = time.Now()
-		fmt.Printf("Changed owner of '%s' to '%s'\n", file, node.Owner)
-		fmt.Printf("Owner of file is %s\n", node.Owner) // Выводит текущего владельца файла
+// Выводит сообщение об ошибке и устанавливает ненулевой статус завершения команды
+func (s *Shell) errorf(format string, args ...any) {
+	fmt.Fprintf(s.errOut(), format, args...)
+	s.status = 1
+}
+
+// Выводит ошибку операции с файлом в виде "cmd: path: reason"
+func (s *Shell) pathError(cmd, path string, err error) {
+	message := fmt.Sprintf("%s: %s: %v", cmd, path, vfs.Reason(err))
+	if s.recordError(newCommandError(message, path, err)) {
+		s.status = 1
+		return
+	}
+	s.errorf("%s\n", message)
+}
+
+// Поток вывода команд: os.Stdout или перенаправленный вывод (конвейер, подстановка команды)
+func (s *Shell) out() io.Writer {
+	if s.stdout != nil {
+		return s.stdout
+	}
+	return os.Stdout
+}
+
+// Поток сообщений об ошибках. Ошибки выводятся на экран даже при перенаправлении вывода
+func (s *Shell) errOut() io.Writer {
+	if s.stderr != nil {
+		return s.stderr
 	}
+	return os.Stdout
+}
+
+// Поток ввода команд: os.Stdin или вывод предыдущей команды конвейера
+func (s *Shell) in() io.Reader {
+	if s.stdin != nil {
+		return s.stdin
+	}
+	return os.Stdin
 }
 func (s *Shell) executeCommand(cmd string, args []string) error {
+	// Псевдонимы раскрываются до поиска функций и команд
+	if value, ok := s.aliases[cmd]; ok && !s.activeAliases[cmd] {
+		s.runAlias(cmd, value, args)
+		return nil
+	}
+	s.lastStatus = s.status
+	s.status = 0
+	if body, ok := s.functions[cmd]; ok {
+		s.callFunction(cmd, body, args)
+		return nil
+	}
 	if handler, exists := s.commands[cmd]; exists {
-		handler(args)
+		// CMD --help выводит справку, сформированную по метаданным команды
+		if info := handler.Info(); !info.NoHelpFlag && wantsHelp(info, args) {
+			fmt.Fprint(s.out(), formatHelp(handler.Info()))
+			return nil
+		}
+		handler.Run(args)
+	} else if strings.Contains(cmd, "/") {
+		// Путь к исполняемому скрипту в VFS
+		return s.runExecutable(cmd, args)
 	} else {
+		s.status = 127
 		return errors.New("сommand doesn`t exists")
 	}
 	return nil
 }
-func (s *Shell) executeScript(scriptPath string) error {
-	file, err := os.Open(scriptPath)
+
+// Выполняет стартовый скрипт. Если echo == true, команды выводятся с приглашением к вводу
+func (s *Shell) executeScript(scriptPath string, echo bool) error {
+	content, err := s.readScript(scriptPath, true)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-	var cmd_err error
-	fmt.Printf("Startup script started work\n")
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		input := strings.TrimSpace(scanner.Text())
-		// пропускаем пустые строки и комментарии
-		if input == "" || strings.HasPrefix(input, "#") {
-			continue
-		}
-		fmt.Printf("%s%s\n", s.getInvitation(), input)
-		cmd, args := parser(input)
-		cmd_err = s.executeCommand(cmd, args)
-		if cmd_err != nil {
-			fmt.Printf("Error: %v\n", cmd_err)
-		}
-	}
-	if err := scanner.Err(); err != nil {
-		return err
-	}
+	s.runScript(content, echo)
 	return nil
 }
 
-// Кастомное приглашение к вводу
-func (s *Shell) getInvitation() string {
-	// Имя пользователя
-	var username string
-	currentUser, err := user.Current()
+// Разбирает строку на команду и аргументы с учетом кавычек и экранирования
+func parseLine(input string) (string, []string, error) {
+	words, err := tokenize(input)
 	if err != nil {
-		username = os.Getenv("USER")
-	} else {
-		username = currentUser.Name
+		return "", nil, err
 	}
-	// Имя хоста
-	hostname, err := os.Hostname()
-	if err != nil {
-		hostname = "localhost"
+	if len(words) == 0 {
+		return "", nil, nil
 	}
-
-	return fmt.Sprintf("%s@%s:~%s$ ", username, hostname, s.currentPath)
+	return words[0], words[1:], nil
 }
 
-// Парсер, который обрабатывает аргументы в кавычках
+// Парсер, который обрабатывает аргументы в кавычках. Ошибки разбора игнорируются
 func parser(cmd string) (string, []string) {
-	cmd = strings.TrimSpace(cmd)
-	if cmd == "" {
-		return "", nil
-	}
-	// регулярное выражение для разбивки с учетом кавычек
-	// [^\s"']+ - последовательность символов без кавычек
-	// "([^"]*)" - последовательность в двойных кавычках
-	// '([^']*)' - в одинарных
-	re := regexp.MustCompile(`[^\s"']+|"([^"]*)"|'([^']*)'`)
-	matches := re.FindAllString(cmd, -1)
-	if len(matches) == 0 {
-		return "", nil
-	}
-	arg := make([]string, 0, len(matches)-1)
-	for i, match := range matches {
-		if i == 0 {
-			continue
-		}
-		if len(match) >= 2 && (match[0] == '"' && match[len(match)-1] == '"' || match[0] == '\'' && match[len(match)-1] == '\'') {
-			match = match[1 : len(match)-1]
-		}
-		arg = append(arg, match)
-	}
-
-	return matches[0], arg
+	name, args, _ := parseLine(cmd)
+	return name, args
 }
 
 func main() {
 	var vfsPath string
 	var startupScript string
+	var command string
 	var help bool
+	var quiet bool
+	var jsonMode bool
+	var outputFormat string
+	var historyFile string
+	var historySize int
+	var rcFile string
+	var noRC bool
+	var quota string
+	var maxNodes int
+	var maxFileSize string
+	var maxDepth int
+	var maxNameLength int
+	var userQuotas string
 
 	// vfs - параметр, -vfs аргументы, если нет аргументов, то vfsPath = ".vfs", "Path to VFS" - текст справки при вызове -help
 	flag.StringVar(&vfsPath, "vfs", ".", "Path to VFS")
 	flag.StringVar(&startupScript, "script", "", "Path to startup script")
+	flag.StringVar(&command, "c", "", "Execute commands from the string and exit with the status of the last one")
 	flag.BoolVar(&help, "help", false, "Show help")
 	flag.BoolVar(&help, "h", false, "Show help")
+	flag.BoolVar(&quiet, "quiet", false, "Do not print the banner, MOTD and script messages")
+	flag.BoolVar(&jsonMode, "json", false, "Same as -output=json")
+	flag.StringVar(&outputFormat, "output", outputText, "Format of command results: text or json")
+	flag.StringVar(&historyFile, "histfile", defaultHistoryFile(), "Path to command history file, empty to keep history in memory")
+	flag.StringVar(&rcFile, "rc", "", "Path to rc file on disk executed in any mode instead of /etc/shellrc and ~/.shellrc in the VFS")
+	flag.BoolVar(&noRC, "norc", false, "Do not execute rc files")
+	flag.IntVar(&historySize, "histsize", defaultHistorySize, "Maximum number of history entries, 0 for unlimited")
+	flag.StringVar(&quota, "quota", defaultQuota, "Maximum total size of file contents in the VFS, e.g. 64M; 0 for unlimited")
+	flag.IntVar(&maxNodes, "max-nodes", 0, "Maximum number of files and directories in the VFS, 0 for unlimited")
+	flag.StringVar(&maxFileSize, "max-file-size", "0", "Maximum size of a single file, e.g. 1M; 0 for unlimited")
+	flag.IntVar(&maxDepth, "max-depth", 0, "Maximum number of path elements, 0 for unlimited")
+	flag.IntVar(&maxNameLength, "max-name-length", 0, "Maximum length of a file name in bytes, 0 for unlimited")
+	flag.StringVar(&userQuotas, "user-quota", "", "Per-owner limits on the total size of files: OWNER=SIZE[,OWNER=SIZE...]")
 
 	flag.Parse()
 
 	if help {
 		flag.Usage()
 	}
-	fmt.Print("Commands:\nls {arguments}\ncd {arguments}\nexit\nvfs-save {path}\nuniq {path}\ntail {flag} {path} (supports only -n {Number of last N lines of file})\n")
-
 	shell := NewShell()
+	if jsonMode {
+		outputFormat = outputJSON
+	}
+	if outputFormat != outputText && outputFormat != outputJSON {
+		fmt.Fprintf(os.Stderr, "Error: unknown output format %q, expected text or json\n", outputFormat)
+		os.Exit(2)
+	}
+	shell.outputFormat = outputFormat
+	limits, err := parseLimits(quota, maxFileSize, userQuotas)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	limits.MaxNodes = maxNodes
+	limits.MaxDepth = maxDepth
+	limits.MaxNameLength = maxNameLength
+	shell.vfs.Limits = limits
+	// Файлы, созданные в оболочке, принадлежат пользователю и учитываются в его квоте
+	shell.vfs.User, _ = lookupHostInfo()
+	// В режиме JSON вывод содержит только результаты команд
+	if outputFormat == outputJSON {
+		quiet = true
+	}
+	// Интерактивный режим - только если команды не заданы через -c и ввод идет с терминала
+	var editor *lineEditor
+	if command == "" {
+		editor = shell.newLineEditor(os.Stdin, os.Stdout)
+	}
+	interactive := editor != nil
+	if !quiet {
+		fmt.Print(shell.usage())
+		fmt.Println("Type 'help' for more information.")
+	}
+
 	if vfsPath != "" {
 		err := shell.vfs.LoadFromDisk(vfsPath)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
+		} else if !quiet {
+			fmt.Printf("VFS loaded from: %v\n", vfsPath)
+		}
+	}
+	// Домашний каталог пользователя, если он есть в VFS: /home/USER
+	if username, _ := lookupHostInfo(); username != "" {
+		if node, err := shell.vfs.FindNode(path.Join("/home", username)); err == nil && node.IsDir {
+			shell.vars["HOME"] = path.Join("/home", username)
 		}
 	}
 
+	if interactive {
+		shell.history.SetLimit(historySize)
+		if historyFile != "" {
+			if err := shell.history.Load(historyFile); err != nil {
+				fmt.Printf("Error: history: %v\n", err)
+			}
+		}
+	}
+	// Файлы конфигурации могут задать псевдонимы, переменные, PS1 и MOTD.
+	// Файлы VFS по умолчанию читаются только в интерактивном режиме,
+	// а явно указанный -rc - в любом
+	if !noRC && (interactive || rcFile != "") {
+		shell.loadRC(rcFile)
+	}
+	if !quiet {
+		shell.printMOTD()
+	}
+
 	if startupScript != "" {
-		if err := shell.executeScript(startupScript); err != nil {
+		if !quiet {
+			fmt.Println("Startup script started work")
+		}
+		if err := shell.executeScript(startupScript, !quiet); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			fmt.Println("Script ended with error")
+			if !quiet {
+				fmt.Println("Script ended with error")
+			}
 			os.Exit(1)
-		} else {
+		} else if !quiet {
 			fmt.Println("Script successfully ended")
 		}
 	}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	for {
-		// Кастомное приглашение к вводу
-		fmt.Print(shell.getInvitation())
-		if !scanner.Scan() {
-			break
+	switch {
+	case command != "":
+		// -c: выполнение команд из строки
+		shell.runScript(command, false)
+	case !interactive:
+		// Ввод не с терминала: команды читаются как скрипт, без приглашения
+		scanner := bufio.NewScanner(os.Stdin)
+		shell.runLines(func(string) (string, bool) {
+			if !scanner.Scan() {
+				return "", false
+			}
+			return scanner.Text(), true
+		}, false)
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		}
-		// считывание ввода
-		input := scanner.Text()
-		cmd, args := parser(input)
+	default:
+		shell.interact(editor)
+	}
+	os.Exit(shell.status)
+}
 
-		if cmd != "" {
-			err := shell.executeCommand(cmd, args)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-			}
+// Интерактивный цикл: строки вводятся через редактор строки
+func (s *Shell) interact(editor *lineEditor) {
+	interrupted := false
+	next := func(prompt string) (string, bool) {
+		line, err := editor.readLine(prompt)
+		if err == errInterrupted {
+			interrupted = true
+			return "", false
+		}
+		if err != nil && err != io.EOF {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		}
+		return line, err == nil
 	}
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	for {
+		// Кастомное приглашение к вводу, продолжение команды запрашивается с приглашением "> "
+		input, err := readInput(next, s.getInvitation())
+		if interrupted {
+			// Ctrl-C отменяет весь ввод, включая начатые строки продолжения
+			interrupted = false
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.syntaxError(err)
+			continue
+		}
+		// Ссылки на историю (!!, !n, !prefix) раскрываются до разбора команды
+		input, ok := s.recordInput(input)
+		if !ok {
+			continue
+		}
+		s.runInput(input)
 	}
 }
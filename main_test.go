@@ -1,10 +1,17 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -12,6 +19,23 @@ import (
 	"github.com/TimofeyChernyshev/MIREA-Configuration-management-1/vfs"
 )
 
+// Перехватывает вывод функции в os.Stdout
+func captureOutput(f func()) string {
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		done <- buf.String()
+	}()
+	f()
+	w.Close()
+	os.Stdout = oldStdout
+	return <-done
+}
+
 func TestParser(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -100,7 +124,7 @@ func TestLsCommand(t *testing.T) {
 	io.Copy(&buf, r)
 	output = buf.String()
 
-	if !strings.Contains(output, "Error") {
+	if !strings.Contains(output, "ls: file1.txt: No such file or directory") {
 		t.Errorf("Expected error message, got: %s", output)
 	}
 
@@ -117,7 +141,7 @@ func TestLsCommand(t *testing.T) {
 	io.Copy(&buf, r)
 	output = buf.String()
 
-	if !strings.Contains(output, "Error") {
+	if !strings.Contains(output, "ls: /nonexistent: No such file or directory") {
 		t.Errorf("Expected error message, got: %s", output)
 	}
 }
@@ -247,6 +271,124 @@ func TestTailCommand(t *testing.T) {
 
 }
 
+func TestTailOptions(t *testing.T) {
+	shell := NewShell()
+	shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+		&vfs.VFSNode{Name: "a", Content: "1\n2\n3\n4\n", ModTime: time.Now()},
+		&vfs.VFSNode{Name: "b", Content: "x\ny", ModTime: time.Now()},
+	)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Завершающий перевод строки не считается пустой строкой
+		{"tail -n 2 /a", "3\n4\n"},
+		{"tail -n 1 /b", "y"},
+		{"tail -n 0 /a", ""},
+		{"tail -n +3 /a", "3\n4\n"},
+		{"tail -n +9 /a", ""},
+		{"tail -c 3 /a", "\n4\n"},
+		{"tail -c +5 /a", "3\n4\n"},
+		{"tail -n 1 /a /b", "==> /a <==\n4\n\n==> /b <==\ny"},
+		{"tail -q -n 1 /a /b", "4\ny"},
+		{"tail -v -n 1 /a", "==> /a <==\n4\n"},
+		{"echo 'p\nq' | tail -n 1", "q\n"},
+		{"tail -n x /a", "tail: invalid number of lines: 'x'\n"},
+		{"tail /nope", "tail: /nope: No such file or directory\n"},
+	}
+	for _, tt := range tests {
+		output := captureOutput(func() { shell.runInput(tt.input) })
+		if output != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, output)
+		}
+	}
+}
+
+func TestTailFollow(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("os.Interrupt cannot be sent to a process on Windows")
+	}
+	shell := NewShell()
+	shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+		&vfs.VFSNode{Name: "log", Content: "start\n", ModTime: time.Now()},
+	)
+	defer func(interval time.Duration) { followInterval = interval }(followInterval)
+	followInterval = 10 * time.Millisecond
+	// Другая горутина дописывает файл, затем tail прерывается, как по Ctrl-C
+	go func() {
+		f, err := shell.vfs.OpenFile("/log", os.O_WRONLY|os.O_APPEND)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer f.Close()
+		time.Sleep(50 * time.Millisecond)
+		f.WriteString("one\n")
+		time.Sleep(50 * time.Millisecond)
+		f.WriteString("two\n")
+		time.Sleep(50 * time.Millisecond)
+		// После обрезки файла tail сообщает об этом и выводит файл с начала
+		f.Truncate(0)
+		f.WriteString("three\n")
+		time.Sleep(50 * time.Millisecond)
+		p, _ := os.FindProcess(os.Getpid())
+		p.Signal(os.Interrupt)
+	}()
+	output := captureOutput(func() { shell.runInput("tail -f /log; echo $?") })
+	if expected := "start\none\ntwo\ntail: /log: file truncated\nthree\n130\n"; output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+
+	shell.outputFormat = outputJSON
+	output = captureOutput(func() { shell.runInput("tail -f /log") })
+	if !strings.Contains(output, `"status":2`) || !strings.Contains(output, "option -f is not supported with JSON output") {
+		t.Errorf("expected tail -f to be rejected in JSON mode, got %q", output)
+	}
+}
+
+func TestBackgroundJobs(t *testing.T) {
+	shell := NewShell()
+	shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+		&vfs.VFSNode{Name: "log", Content: "old\n", ModTime: time.Now()},
+	)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Основная оболочка не ждет задание, пока не вызван wait
+		{"{ sleep 0.05; echo job; } & echo fg; wait $!; echo $?", "fg\njob\n0\n"},
+		{"false & wait %$!; echo $?", "1\n"},
+		{"{ sleep 0.05; exit 3; } & true & wait; echo $?", "0\n"},
+		{"wait 9; echo $?", "wait: 9: no such job\n127\n"},
+		{"sleep 1x; echo $?", "sleep: invalid time interval '1x'\n1\n"},
+		{"echo a | tee /out; echo b | tee -a /out | true; tail /out", "a\na\nb\n"},
+	}
+	for _, test := range tests {
+		output := captureOutput(func() { shell.runInput(test.input) })
+		if output != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.input, test.expected, output)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	defer func(interval time.Duration) { followInterval = interval }(followInterval)
+	followInterval = 10 * time.Millisecond
+	// Фоновое задание дописывает файл, пока выполняется tail -f
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		p, _ := os.FindProcess(os.Getpid())
+		p.Signal(os.Interrupt)
+	}()
+	output := captureOutput(func() {
+		shell.runInput("{ sleep 0.05; echo new | tee -a /log | true; } & tail -f /log; echo $?; wait")
+	})
+	if expected := "old\nnew\n130\n"; output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+}
+
 func TestMvCommand(t *testing.T) {
 	shell := NewShell()
 
@@ -308,3 +450,1296 @@ func TestChownCommand(t *testing.T) {
 		t.Errorf("Expected owner 'newuser', got '%s'", node.Owner)
 	}
 }
+
+func TestRegisterCommand(t *testing.T) {
+	shell := NewShell()
+
+	var got []string
+	cmd := &FuncCommand{
+		CommandInfo: CommandInfo{Name: "greet", Usage: "greet NAME", Short: "print greeting"},
+		Handler:     func(args []string) { got = args },
+	}
+	if err := shell.Register(cmd); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := shell.executeCommand("greet", []string{"world"}); err != nil {
+		t.Fatalf("executeCommand failed: %v", err)
+	}
+	if len(got) != 1 || got[0] != "world" {
+		t.Errorf("Expected handler args [world], got %v", got)
+	}
+
+	// Повторная регистрация должна завершиться ошибкой
+	if err := shell.Register(cmd); err == nil {
+		t.Error("Expected error on duplicate registration")
+	}
+
+	if !strings.Contains(shell.usage(), "greet NAME") {
+		t.Errorf("Expected usage to contain registered command, got: %s", shell.usage())
+	}
+}
+
+func TestHelpCommand(t *testing.T) {
+	shell := NewShell()
+	capture := captureOutput
+
+	// help без аргументов перечисляет все зарегистрированные команды
+	output := capture(func() { shell.helpCommand([]string{}) })
+	for _, cmd := range shell.Commands() {
+		if !strings.Contains(output, cmd.Info().Name) {
+			t.Errorf("Expected '%s' in help output, got: %s", cmd.Info().Name, output)
+		}
+	}
+
+	output = capture(func() { shell.executeCommand("tail", []string{"--help"}) })
+	if !strings.Contains(output, "Usage: tail") || !strings.Contains(output, "-n N") {
+		t.Errorf("Expected tail usage with options, got: %s", output)
+	}
+
+	// cd и exit разбирают аргументы так же, как остальные команды
+	shell.vfs.Root.Children = append(shell.vfs.Root.Children, &vfs.VFSNode{Name: "-dir", IsDir: true, ModTime: time.Now()})
+	output = capture(func() { shell.runInput("cd --help; cd -- -dir; echo $?; cd -x; echo $?; exit -x; echo $?") })
+	if !strings.HasPrefix(output, "Usage: cd [PATH]\n") ||
+		!strings.HasSuffix(output, "0\ncd: invalid option -- 'x'\nTry 'cd --help' for more information.\n2\n"+
+			"exit: invalid option -- 'x'\nTry 'exit --help' for more information.\n2\n") {
+		t.Errorf("Expected cd and exit to use the option parser, got: %q", output)
+	}
+	if shell.currentPath != "/-dir" {
+		t.Errorf("Expected cd -- -dir to change to /-dir, got %s", shell.currentPath)
+	}
+	shell.currentPath = "/"
+
+	// test, [ и echo получают --help как обычный операнд
+	output = capture(func() {
+		shell.runInput(`x=--help; if [ "$x" = --help ]; then echo yes; fi; if [ "$x" = other ]; then echo no; fi; echo --help; test --help; echo $?`)
+	})
+	if output != "yes\n--help\n0\n" {
+		t.Errorf("Expected --help to be passed to [, echo and test, got: %q", output)
+	}
+
+	output = capture(func() { shell.manCommand([]string{"mv"}) })
+	if !strings.Contains(output, "NAME") || !strings.Contains(output, "mv - move or rename files") {
+		t.Errorf("Expected man page for mv, got: %s", output)
+	}
+
+	output = capture(func() { shell.manCommand([]string{"nonexistent"}) })
+	if !strings.Contains(output, "no manual entry") {
+		t.Errorf("Expected error for unknown command, got: %s", output)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	specs := []FlagSpec{
+		{Short: "n", Long: "lines", Arg: "N"},
+		{Short: "r"},
+		{Short: "f", Long: "force"},
+	}
+	tests := []struct {
+		name             string
+		args             []string
+		expectedOpts     map[string]string
+		expectedOperands []string
+		expectedErr      string
+	}{
+		{
+			name:             "separate value",
+			args:             []string{"-n", "5", "file"},
+			expectedOpts:     map[string]string{"n": "5"},
+			expectedOperands: []string{"file"},
+		},
+		{
+			name:             "attached value",
+			args:             []string{"-n5", "file"},
+			expectedOpts:     map[string]string{"lines": "5"},
+			expectedOperands: []string{"file"},
+		},
+		{
+			name:             "combined short flags",
+			args:             []string{"-rf", "a", "b"},
+			expectedOpts:     map[string]string{"r": "", "f": ""},
+			expectedOperands: []string{"a", "b"},
+		},
+		{
+			name:             "long flag with =",
+			args:             []string{"file", "--lines=3"},
+			expectedOpts:     map[string]string{"n": "3"},
+			expectedOperands: []string{"file"},
+		},
+		{
+			name:             "end of options",
+			args:             []string{"-r", "--", "-f"},
+			expectedOpts:     map[string]string{"r": ""},
+			expectedOperands: []string{"-f"},
+		},
+		{
+			name:        "invalid option",
+			args:        []string{"-x"},
+			expectedErr: "invalid option -- 'x'",
+		},
+		{
+			name:        "missing argument",
+			args:        []string{"-n"},
+			expectedErr: "option requires an argument -- 'n'",
+		},
+		{
+			name:        "unrecognized long option",
+			args:        []string{"--verbose"},
+			expectedErr: "unrecognized option '--verbose'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, operands, err := parseOptions("test", specs, tt.args, false)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("expected error %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for name, value := range tt.expectedOpts {
+				if !opts.Has(name) || opts.Value(name) != value {
+					t.Errorf("expected option %s=%q, got %q", name, value, opts.Value(name))
+				}
+			}
+			if strings.Join(operands, " ") != strings.Join(tt.expectedOperands, " ") {
+				t.Errorf("expected operands %v, got %v", tt.expectedOperands, operands)
+			}
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedWords []string
+		expectedErr   string
+	}{
+		{
+			name:          "adjacent quoted and unquoted segments",
+			input:         `echo "a"b'c'`,
+			expectedWords: []string{"echo", "abc"},
+		},
+		{
+			name:          "backslash escapes",
+			input:         `ls my\ dir \"x\"`,
+			expectedWords: []string{"ls", "my dir", `"x"`},
+		},
+		{
+			name:          "escaped quote inside double quotes",
+			input:         `echo "say \"hi\" \n"`,
+			expectedWords: []string{"echo", `say "hi" \n`},
+		},
+		{
+			name:          "single quotes are literal",
+			input:         `echo 'a\b "c"'`,
+			expectedWords: []string{"echo", `a\b "c"`},
+		},
+		{
+			name:          "command substitution is one word",
+			input:         `echo $(ls "a b" | wc -l)x "$(echo ")")"`,
+			expectedWords: []string{"echo", `$(ls a b | wc -l)x`, `$(echo ))`},
+		},
+		{
+			name:          "empty quoted argument",
+			input:         `cd ""`,
+			expectedWords: []string{"cd", ""},
+		},
+		{
+			name:          "comment",
+			input:         "ls / # list root",
+			expectedWords: []string{"ls", "/"},
+		},
+		{
+			name:        "unterminated double quote",
+			input:       `ls "abc`,
+			expectedErr: "unterminated quote",
+		},
+		{
+			name:        "unterminated single quote",
+			input:       `ls 'abc`,
+			expectedErr: "unterminated quote",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			words, err := tokenize(tt.input)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("expected error %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fmt.Sprintf("%q", words) != fmt.Sprintf("%q", tt.expectedWords) {
+				t.Errorf("expected words %q, got %q", tt.expectedWords, words)
+			}
+		})
+	}
+}
+
+func FuzzParser(f *testing.F) {
+	for _, seed := range []string{"ls", "ls arg", "ls \"arg1 arg2\"", "", `echo "a"b'c'`, `a\ b 'c\'`} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		words, err := tokenize(input)
+		if err != nil {
+			return
+		}
+		// Слова, заключенные в кавычки через quoteWord, должны разбираться обратно без изменений
+		quoted := make([]string, len(words))
+		for i, word := range words {
+			quoted[i] = quoteWord(word)
+		}
+		again, err := tokenize(strings.Join(quoted, " "))
+		if err != nil {
+			t.Fatalf("re-tokenize %q: %v", quoted, err)
+		}
+		if fmt.Sprintf("%q", again) != fmt.Sprintf("%q", words) {
+			t.Errorf("round trip mismatch: %q -> %q", words, again)
+		}
+	})
+}
+
+func TestReadInput(t *testing.T) {
+	tests := []struct {
+		name            string
+		lines           []string
+		expectedInput   string
+		expectedPrompts int
+	}{
+		{
+			name:            "single line",
+			lines:           []string{"ls /"},
+			expectedInput:   "ls /",
+			expectedPrompts: 1,
+		},
+		{
+			name:            "backslash continuation",
+			lines:           []string{`ls \`, "/"},
+			expectedInput:   "ls \\\n/",
+			expectedPrompts: 2,
+		},
+		{
+			name:            "open quote continuation",
+			lines:           []string{`cd "a`, `b"`},
+			expectedInput:   "cd \"a\nb\"",
+			expectedPrompts: 2,
+		},
+		{
+			name:            "multi-line control structure",
+			lines:           []string{"for x in a b", "do", "ls $x", "done"},
+			expectedInput:   "for x in a b\ndo\nls $x\ndone",
+			expectedPrompts: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prompts []string
+			lines := tt.lines
+			next := func(prompt string) (string, bool) {
+				prompts = append(prompts, prompt)
+				if len(lines) == 0 {
+					return "", false
+				}
+				line := lines[0]
+				lines = lines[1:]
+				return line, true
+			}
+			input, err := readInput(next, "$ ")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if input != tt.expectedInput {
+				t.Errorf("expected input %q, got %q", tt.expectedInput, input)
+			}
+			if len(prompts) != tt.expectedPrompts {
+				t.Errorf("expected %d prompts, got %v", tt.expectedPrompts, prompts)
+			}
+			for _, prompt := range prompts[1:] {
+				if prompt != secondaryPrompt {
+					t.Errorf("expected secondary prompt %q, got %q", secondaryPrompt, prompt)
+				}
+			}
+		})
+	}
+
+	// Ввод, оборванный посреди кавычек, завершается ошибкой
+	lines := []string{`ls "abc`}
+	_, err := readInput(func(string) (string, bool) {
+		if len(lines) == 0 {
+			return "", false
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return line, true
+	}, "$ ")
+	if err == nil {
+		t.Error("Expected error for unterminated input at end of file")
+	}
+}
+
+func TestControlFlow(t *testing.T) {
+	newTestShell := func() *Shell {
+		shell := NewShell()
+		shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+			&vfs.VFSNode{Name: "dir", IsDir: true, ModTime: time.Now(), Children: []*vfs.VFSNode{
+				{Name: "a.txt", Content: "a", ModTime: time.Now()},
+				{Name: "b.txt", ModTime: time.Now()},
+				{Name: "c.log", ModTime: time.Now()},
+			}},
+		)
+		return shell
+	}
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+	}{
+		{
+			name:     "if elif else",
+			script:   "if [ -f /dir ]; then echo file; elif [ -d /dir ]; then echo dir; else echo none; fi",
+			expected: "dir\n",
+		},
+		{
+			name:     "for with glob over VFS",
+			script:   "for f in /dir/*.txt; do echo $f; done",
+			expected: "/dir/a.txt\n/dir/b.txt\n",
+		},
+		{
+			name:     "relative glob",
+			script:   "cd /dir; for f in *; do echo $f; done",
+			expected: "a.txt\nb.txt\nc.log\n",
+		},
+		{
+			name:     "while with break",
+			script:   "x=a\nwhile true; do\n  echo $x\n  if [ $x = aaa ]; then break; fi\n  x=${x}a\ndone",
+			expected: "a\naa\naaa\n",
+		},
+		{
+			name:     "until",
+			script:   "n=\nuntil [ \"$n\" = xx ]; do n=x$n; done; echo $n",
+			expected: "xx\n",
+		},
+		{
+			name:     "case with patterns",
+			script:   "for f in a.txt b.log c; do case $f in *.txt) echo text;; *.log|*.out) echo log;; *) echo other;; esac; done",
+			expected: "text\nlog\nother\n",
+		},
+		{
+			name:     "and or with test",
+			script:   "test -s /dir/a.txt && echo nonempty; test -s /dir/b.txt || echo empty",
+			expected: "nonempty\nempty\n",
+		},
+		{
+			name:     "integer and string comparisons",
+			script:   "[ 10 -gt 9 -a abc != abd ] && echo yes; [ ! 1 -eq 1 ] || echo no",
+			expected: "yes\nno\n",
+		},
+		{
+			name:     "exit status",
+			script:   "false; echo $?; [ -e /missing ]; echo $?",
+			expected: "1\n1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shell := newTestShell()
+			output := captureOutput(func() { shell.runInput(tt.script) })
+			if output != tt.expected {
+				t.Errorf("expected output %q, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestFunctionsAndAliases(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+	}{
+		{
+			name:     "function with positional parameters",
+			script:   "greet() { echo \"hello $1 ($#)\"; }\ngreet world extra",
+			expected: "hello world (2)\n",
+		},
+		{
+			name:     "quoted $@ keeps arguments",
+			script:   "count() { echo $#; }\nwrap() { count \"$@\"; }\nwrap 'a b' c",
+			expected: "2\n",
+		},
+		{
+			name:     "return status",
+			script:   "check() {\n  if [ \"$1\" = ok ]; then return 0; fi\n  return 3\n}\ncheck ok; echo $?; check bad; echo $?",
+			expected: "0\n3\n",
+		},
+		{
+			name:     "return from loop inside function",
+			script:   "first() { for x in a b c; do if [ $x = b ]; then echo $x; return; fi; done; echo never; }\nfirst",
+			expected: "b\n",
+		},
+		{
+			name:     "shift",
+			script:   "f() { shift; echo $1; }; f a b",
+			expected: "b\n",
+		},
+		{
+			name:     "alias expanded before command lookup",
+			script:   "alias hi='echo hi'\nhi there",
+			expected: "hi there\n",
+		},
+		{
+			name:     "recursive alias is not expanded twice",
+			script:   "alias echo='echo prefix'\necho x",
+			expected: "prefix x\n",
+		},
+		{
+			name:     "unalias",
+			script:   "alias e='echo aliased'; unalias e; e",
+			expected: "Error: сommand doesn`t exists\n",
+		},
+		{
+			name:     "recursion limit",
+			script:   "f() { f; }; f",
+			expected: fmt.Sprintf("f: maximum nesting level exceeded (%d)\n", maxCallDepth),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shell := NewShell()
+			output := captureOutput(func() { shell.runInput(tt.script) })
+			if output != tt.expected {
+				t.Errorf("expected output %q, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestSourceAndSh(t *testing.T) {
+	newTestShell := func() *Shell {
+		shell := NewShell()
+		shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+			&vfs.VFSNode{Name: "bin", IsDir: true, ModTime: time.Now(), Children: []*vfs.VFSNode{
+				{Name: "setx.sh", Content: "x=sourced\nsetx_args=$#\n", ModTime: time.Now()},
+				{Name: "child.sh", Content: "#!/bin/sh\ncd /bin\necho \"child $1\"\nexit 4\necho unreachable\n", ModTime: time.Now()},
+				{Name: "early.sh", Content: "echo before\nreturn 5\necho after\n", ModTime: time.Now()},
+				{Name: "self.sh", Content: "source /bin/self.sh\n", ModTime: time.Now()},
+				{Name: "py", Content: "#!/usr/bin/python3\nprint(1)\n", ModTime: time.Now()},
+			}},
+		)
+		return shell
+	}
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+	}{
+		{
+			name:     "source runs in current context",
+			script:   "source /bin/setx.sh a b; echo $x $setx_args",
+			expected: "sourced 2\n",
+		},
+		{
+			name:     "dot is an alias for source",
+			script:   "cd /bin; . setx.sh; echo $x",
+			expected: "sourced\n",
+		},
+		{
+			name:     "return stops sourced script",
+			script:   "source /bin/early.sh; echo $?",
+			expected: "before\n5\n",
+		},
+		{
+			name:     "sh runs in child context",
+			script:   "sh /bin/child.sh arg; echo $?",
+			expected: "child arg\n4\n",
+		},
+		{
+			name:     "sh -c",
+			script:   "x=parent; sh -c 'x=child; echo $x $1' one; echo $x",
+			expected: "child one\nparent\n",
+		},
+		{
+			name:     "flags after script are passed to it",
+			script:   "sh /bin/child.sh -x --help; source /bin/setx.sh -n --help; echo $setx_args",
+			expected: "child -x\n2\n",
+		},
+		{
+			name:     "sh -c with flag-like parameters",
+			script:   "sh -c 'echo $1 $2' -a -b",
+			expected: "-a -b\n",
+		},
+		{
+			name:     "shebang script executed by path",
+			script:   "/bin/child.sh direct; echo $?",
+			expected: "child direct\n4\n",
+		},
+		{
+			name:     "unknown interpreter",
+			script:   "/bin/py",
+			expected: "Error: /bin/py: bad interpreter: /usr/bin/python3\n",
+		},
+		{
+			name:     "recursion depth limit",
+			script:   "source /bin/self.sh; echo $?",
+			expected: fmt.Sprintf("source: /bin/self.sh: maximum nesting level exceeded (%d)\n1\n", maxCallDepth),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shell := newTestShell()
+			output := captureOutput(func() { shell.runInput(tt.script) })
+			if output != tt.expected {
+				t.Errorf("expected output %q, got %q", tt.expected, output)
+			}
+			if shell.currentPath != "/" && !strings.Contains(tt.script, "cd /bin;") {
+				t.Errorf("child context changed current path to %s", shell.currentPath)
+			}
+		})
+	}
+}
+
+func TestSubstitutionAndArithmetic(t *testing.T) {
+	newTestShell := func() *Shell {
+		shell := NewShell()
+		shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+			&vfs.VFSNode{Name: "test_vfs", IsDir: true, ModTime: time.Now(), Children: []*vfs.VFSNode{
+				{Name: "a.txt", Content: "one two\nthree\n", ModTime: time.Now()},
+				{Name: "b.txt", Content: "four\n", ModTime: time.Now()},
+			}},
+		)
+		return shell
+	}
+	tests := []struct {
+		name     string
+		script   string
+		expected string
+	}{
+		{"substitution with pipeline", `echo "files: $(ls /test_vfs | wc -l)"`, "files: 2\n"},
+		{"nested substitution", "echo $(echo $(echo nested))", "nested\n"},
+		{"trailing newlines trimmed", `x="$(echo a; echo b)"; echo "[$x]"`, "[a\nb]\n"},
+		{"unquoted substitution is split", "for w in $(echo a b c); do echo $w; done", "a\nb\nc\n"},
+		{"substitution runs in subshell", "x=1; y=$(x=2; echo $x); echo $x $y", "1 2\n"},
+		{"assignment status from substitution", "x=$(false); echo $?", "1\n"},
+		{"functions visible in substitution", "f() { echo in-f; }; echo $(f)", "in-f\n"},
+		{"wc on files", "wc /test_vfs/a.txt /test_vfs/b.txt",
+			"      2       3      14 /test_vfs/a.txt\n      1       1       5 /test_vfs/b.txt\n      3       4      19 total\n"},
+		{"pipeline status is last command", "false | true; echo $?", "0\n"},
+		{"arithmetic", "n=4; echo $((n + 1)) $((2 ** 10)) $(( (1 + 2) * 3 )) $((7 % 3)) $((-n))", "5 1024 9 1 -4\n"},
+		{"arithmetic comparison and logic", "echo $((3 > 2)) $((1 && 0)) $((0 || 2)) $((!5)) $((4 > 3 ? 10 : 20))", "1 0 1 0 10\n"},
+		{"arithmetic bit operators", "echo $((1 << 4)) $((6 & 3)) $((6 | 3)) $((6 ^ 3)) $((~0))", "16 2 7 5 -1\n"},
+		{"arithmetic assignment", "i=0; echo $((i += 5)) $((i++)) $i $((--i)) $((j = 3)) $j", "5 5 6 5 3 3\n"},
+		{"arithmetic in loop", "i=0; while [ $i -lt 3 ]; do i=$((i + 1)); done; echo $i", "3\n"},
+		{"arithmetic with parameters", "f() { echo $(($1 * $2)); }; f 6 7", "42\n"},
+		{"division by zero", "echo $((1 / 0)); echo $?", "Error: division by 0\n1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shell := newTestShell()
+			output := captureOutput(func() { shell.runInput(tt.script) })
+			if output != tt.expected {
+				t.Errorf("expected output %q, got %q", tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	history := NewHistory(0)
+	for _, line := range []string{"echo one", "ls /", "echo two", "  ", "cd /tmp"} {
+		history.Add(line)
+	}
+	expandTests := []struct {
+		input       string
+		expected    string
+		expectedErr string
+	}{
+		{"!!", "cd /tmp", ""},
+		{"!1", "echo one", ""},
+		{"!-2", "echo two", ""},
+		{"!ec", "echo two", ""},
+		{"!l && !!", "ls / && cd /tmp", ""},
+		{"echo '!!' \\!! \"!1\"", "echo '!!' \\!! \"echo one\"", ""},
+		{"[ ! -f x ] && [ a != b ]", "[ ! -f x ] && [ a != b ]", ""},
+		{"!7", "", "!7: event not found"},
+		{"!nope", "", "!nope: event not found"},
+	}
+	for _, tt := range expandTests {
+		result, err := history.Expand(tt.input)
+		if tt.expectedErr != "" {
+			if err == nil || err.Error() != tt.expectedErr {
+				t.Errorf("Expand(%q): expected error %q, got %v", tt.input, tt.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil || result != tt.expected {
+			t.Errorf("Expand(%q): expected %q, got %q (err %v)", tt.input, tt.expected, result, err)
+		}
+	}
+
+	// Ограничение размера: номера записей не сдвигаются
+	history.SetLimit(2)
+	if _, ok := history.Get(2); ok {
+		t.Errorf("entry 2 should be dropped by the size limit")
+	}
+	if entry, _ := history.Get(4); entry != "cd /tmp" {
+		t.Errorf("expected entry 4 to be %q, got %q", "cd /tmp", entry)
+	}
+
+	// Сохранение и загрузка, включая многострочные команды
+	path := filepath.Join(t.TempDir(), ".vfs_history")
+	saved := NewHistory(10)
+	saved.Load(path)
+	saved.Add("for i in 1 2\ndo echo $i\ndone")
+	saved.Add(`echo a\b`)
+	loaded := NewHistory(10)
+	if err := loaded.Load(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Entries(), saved.Entries()) {
+		t.Errorf("expected entries %q, got %q", saved.Entries(), loaded.Entries())
+	}
+
+	// Команда history
+	shell := NewShell()
+	for _, line := range []string{"echo a", "!!", "history 2"} {
+		output := captureOutput(func() {
+			if input, ok := shell.recordInput(line); ok {
+				shell.runInput(input)
+			}
+		})
+		if line == "history 2" && output != "    2  echo a\n    3  history 2\n" {
+			t.Errorf("unexpected history output %q", output)
+		}
+		if line == "!!" && output != "echo a\na\n" {
+			t.Errorf("unexpected output of !! %q", output)
+		}
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	shell := NewShell()
+	shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+		&vfs.VFSNode{Name: "test_vfs", IsDir: true, ModTime: time.Now(), Children: []*vfs.VFSNode{
+			{Name: "file1.txt", ModTime: time.Now()},
+			{Name: "file2.txt", ModTime: time.Now()},
+			{Name: "my dir", IsDir: true, ModTime: time.Now()},
+		}},
+	)
+	shell.history.Add("echo first")
+	shell.history.Add("ls /test_vfs")
+	tests := []struct {
+		name        string
+		keys        string
+		expected    string
+		expectedErr error
+	}{
+		{"plain input", "echo hi\r", "echo hi", nil},
+		{"cursor movement and insert", "eho\x1b[D\x1b[Dc\x1b[F!\r", "echo!", nil},
+		{"home end and delete", "xecho\x01\x1b[3~\x05 a\r", "echo a", nil},
+		{"backspace and kill", "echo abc\x7f\x7f\x17x\x01\x0bls\r", "ls", nil},
+		{"history up and down", "\x1b[A\x1b[A\x1b[B\r", "ls /test_vfs", nil},
+		{"history keeps edited line", "new\x1b[A\x1b[B\r", "new", nil},
+		{"reverse search", "\x12ech\r", "echo first", nil},
+		{"reverse search accept for editing", "\x12ls\x05 -l\r", "ls /test_vfs -l", nil},
+		{"complete command", "ec\thi\r", "echo hi", nil},
+		{"complete path", "ls /te\t\r", "ls /test_vfs/", nil},
+		{"complete common prefix", "ls /test_vfs/f\t\r", "ls /test_vfs/file", nil},
+		{"complete escaped name", "cd /test_vfs/m\t\r", "cd /test_vfs/my\\ dir/", nil},
+		{"ctrl-d on empty line", "\x04", "", io.EOF},
+		{"ctrl-c", "echo\x03", "", errInterrupted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			editor := &lineEditor{
+				in:       bufio.NewReader(strings.NewReader(tt.keys)),
+				out:      &out,
+				history:  shell.history.Entries,
+				complete: shell.completions,
+			}
+			line, err := editor.readLine("$ ")
+			if err != tt.expectedErr {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if line != tt.expected {
+				t.Errorf("expected line %q, got %q", tt.expected, line)
+			}
+		})
+	}
+
+	candidates, start := shell.completions("ls /test_vfs/file1.txt; un")
+	if !reflect.DeepEqual(candidates, []string{"unalias", "uniq", "unset"}) || start != 24 {
+		t.Errorf("unexpected command completions %q at %d", candidates, start)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	username, hostname := lookupHostInfo()
+	sign := "$"
+	if os.Geteuid() == 0 {
+		sign = "#"
+	}
+	tests := []struct {
+		name     string
+		ps1      string
+		cwd      string
+		expected string
+	}{
+		{"default prompt", defaultPrompt, "/test_vfs", username + "@" + strings.SplitN(hostname, ".", 2)[0] + ":/test_vfs" + sign + " "},
+		{"home abbreviation", `\w|\W`, "/home/user/docs", "~/docs|docs"},
+		{"home directory itself", `\w|\W`, "/home/user", "~|~"},
+		{"similar prefix is not home", `\w`, "/home/username", "/home/username"},
+		{"root directory", `\w|\W`, "/", "/|/"},
+		{"exit status and variables", `[\?] $x "$((1 + 2))"\$$ `, "/", `[1] value "3"` + sign + "$ "},
+		{"escapes", `\s\n\e[1m\[\]\101\\`, "/", "vfs-shell\n\x1b[1mA\\"},
+		{"substituted values are not expanded", `\w`, "/$x", "/$x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shell := NewShell()
+			shell.vars["HOME"] = "/home/user"
+			shell.vars["x"] = "value"
+			shell.vars["PS1"] = tt.ps1
+			shell.currentPath = tt.cwd
+			shell.status = 1
+			if prompt := shell.getInvitation(); prompt != tt.expected {
+				t.Errorf("expected prompt %q, got %q", tt.expected, prompt)
+			}
+		})
+	}
+}
+
+func TestRCFile(t *testing.T) {
+	newTestShell := func() *Shell {
+		shell := NewShell()
+		shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+			&vfs.VFSNode{Name: "motd", Content: "Welcome!", ModTime: time.Now()},
+			&vfs.VFSNode{Name: "news", Content: "News of the day", ModTime: time.Now()},
+			&vfs.VFSNode{Name: "etc", IsDir: true, ModTime: time.Now(), Children: []*vfs.VFSNode{
+				{Name: "shellrc", Content: "alias h='history 10'\nHOME=/home/user\n", ModTime: time.Now()},
+			}},
+			&vfs.VFSNode{Name: "home", IsDir: true, ModTime: time.Now(), Children: []*vfs.VFSNode{
+				{Name: "user", IsDir: true, ModTime: time.Now(), Children: []*vfs.VFSNode{
+					{Name: ".shellrc", Content: "PS1='\\W> '\nMOTD=/news\nreturn\nMOTD=\n", ModTime: time.Now()},
+				}},
+			}},
+		)
+		return shell
+	}
+
+	shell := newTestShell()
+	output := captureOutput(func() {
+		shell.loadRC("")
+		shell.printMOTD()
+	})
+	if output != "News of the day\n" {
+		t.Errorf("unexpected output %q", output)
+	}
+	if shell.aliases["h"] != "history 10" || shell.vars["PS1"] != `\W> ` {
+		t.Errorf("rc files were not applied: aliases %v, PS1 %q", shell.aliases, shell.vars["PS1"])
+	}
+
+	// Файл с диска заменяет файлы конфигурации из VFS
+	rcPath := filepath.Join(t.TempDir(), "shellrc")
+	if err := os.WriteFile(rcPath, []byte("greeting=hello\nMOTD=\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	shell = newTestShell()
+	output = captureOutput(func() {
+		shell.loadRC(rcPath)
+		shell.printMOTD()
+	})
+	if output != "" || shell.vars["greeting"] != "hello" || shell.aliases["ll"] != "" {
+		t.Errorf("unexpected state after host rc file: output %q, vars %v, aliases %v", output, shell.vars, shell.aliases)
+	}
+
+	// Без файлов конфигурации выводится /motd
+	shell = newTestShell()
+	output = captureOutput(shell.printMOTD)
+	if output != "Welcome!\n" {
+		t.Errorf("expected default MOTD, got %q", output)
+	}
+}
+
+func TestRunLines(t *testing.T) {
+	// Строки запрашиваются по мере выполнения, как при чтении скрипта со стандартного ввода
+	lines := []string{"x=1", "while [ $x -lt 3 ]; do", "x=$((x + 1))", "done", "echo $x", "false"}
+	var requested []int
+	shell := NewShell()
+	output := captureOutput(func() {
+		shell.runLines(func(prompt string) (string, bool) {
+			requested = append(requested, len(lines))
+			if len(lines) == 0 {
+				return "", false
+			}
+			line := lines[0]
+			lines = lines[1:]
+			return line, true
+		}, false)
+	})
+	if output != "3\n" {
+		t.Errorf("expected output %q, got %q", "3\n", output)
+	}
+	if shell.status != 1 {
+		t.Errorf("expected status of the last command 1, got %d", shell.status)
+	}
+	if len(requested) != 7 {
+		t.Errorf("expected 7 line requests, got %d", len(requested))
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	shell := NewShell()
+	shell.outputFormat = outputJSON
+	shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+		&vfs.VFSNode{Name: "dir", IsDir: true, ModTime: time.Now(), Children: []*vfs.VFSNode{
+			{Name: "a.txt", Content: "one\ntwo\n", Owner: "root", ModTime: time.Now()},
+		}},
+	)
+	output := captureOutput(func() {
+		shell.runInput("ls /dir; mv /dir/a.txt /dir/b.txt; chown bob /dir/b.txt; echo hi; ls /dir | wc -l; ls /missing; nosuch")
+	})
+	var records []commandResult
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var record commandResult
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 7 {
+		t.Fatalf("expected 7 records, got %d: %s", len(records), output)
+	}
+	expected := []struct {
+		command string
+		status  int
+		results string
+		output  string
+		errors  int
+	}{
+		{"ls", 0, `[{"name":"a.txt","owner":"root","path":"/dir","size":8,"type":"file"}]`, "", 0},
+		{"mv", 0, `[{"destination":"/dir/b.txt","source":"/dir/a.txt"}]`, "", 0},
+		{"chown", 0, `[{"file":"/dir/b.txt","owner":"bob"}]`, "", 0},
+		{"echo", 0, "null", "hi\n", 0},
+		{"wc", 0, `[{"bytes":6,"lines":1,"words":1}]`, "", 0},
+		{"ls", 1, "null", "", 1},
+		{"nosuch", 127, "null", "", 1},
+	}
+	for i, want := range expected {
+		record := records[i]
+		// Время изменения зависит от момента запуска теста и не сравнивается
+		for _, result := range record.Results {
+			if entry, ok := result.(map[string]any); ok {
+				delete(entry, "modTime")
+			}
+		}
+		results, _ := json.Marshal(record.Results)
+		if want.command == "ls" && want.errors == 1 {
+			expectedErr := commandError{Message: "ls: /missing: No such file or directory", Code: "ENOENT", Path: "/missing", Reason: "No such file or directory"}
+			if len(record.Errors) != 1 || record.Errors[0] != expectedErr {
+				t.Errorf("record %d: expected error %+v, got %+v", i, expectedErr, record.Errors)
+			}
+		}
+		if record.Command != want.command || record.Status != want.status || string(results) != want.results ||
+			record.Output != want.output || len(record.Errors) != want.errors {
+			t.Errorf("record %d: expected %+v, got %+v (results %s)", i, want, record, results)
+		}
+	}
+
+	// Команды дочернего контекста добавляют результаты к записи sh,
+	// а вывод подстановки остается текстом
+	output = captureOutput(func() { shell.runInput("sh -c 'ls /dir; wc -l /dir/b.txt'; echo $(ls /dir)") })
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records, got %q", output)
+	}
+	var record commandResult
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil || record.Command != "sh" || len(record.Results) != 2 || record.Output != "" {
+		t.Errorf("expected sh record with ls and wc results, got %s", lines[0])
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil || record.Output != "b.txt\n" {
+		t.Errorf("expected echo record with substituted text, got %s", lines[1])
+	}
+}
+
+func TestVFSErrors(t *testing.T) {
+	shell := NewShell()
+	shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+		&vfs.VFSNode{Name: "dir", IsDir: true, ModTime: time.Now(), Children: []*vfs.VFSNode{
+			{Name: "a.txt", Content: "one\n", ModTime: time.Now()},
+			{Name: "b.txt", Content: "two\n", ModTime: time.Now()},
+		}},
+	)
+	errorCases := []struct {
+		err    error
+		target error
+		op     string
+	}{
+		{func() error { _, err := shell.vfs.FindNode("/missing"); return err }(), vfs.ErrNotExist, "find"},
+		{func() error { _, err := shell.vfs.FindNode("/dir/a.txt/x"); return err }(), vfs.ErrNotDir, "find"},
+		{shell.vfs.MoveNode("/dir/a.txt", "/dir/b.txt"), vfs.ErrExist, "rename"},
+		{shell.vfs.RemoveNode("/"), vfs.ErrPermission, "remove"},
+		{shell.vfs.RemoveNode("/dir/none"), vfs.ErrNotExist, "remove"},
+	}
+	for i, tc := range errorCases {
+		var pathErr *vfs.PathError
+		if !errors.As(tc.err, &pathErr) || pathErr.Op != tc.op {
+			t.Errorf("case %d: expected *PathError with op %q, got %#v", i, tc.op, tc.err)
+		}
+		if !errors.Is(tc.err, tc.target) {
+			t.Errorf("case %d: expected errors.Is(%v, %v)", i, tc.err, tc.target)
+		}
+	}
+	// Ошибки VFS совпадают с ошибками io/fs
+	if _, err := shell.vfs.FindNode("/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+
+	output := captureOutput(func() {
+		shell.runInput("ls /missing; cd /dir/a.txt; mv /dir/a.txt /dir/b.txt; source /dir")
+	})
+	expected := "ls: /missing: No such file or directory\n" +
+		"cd: /dir/a.txt: Not a directory\n" +
+		"mv: /dir/b.txt: File exists\n" +
+		"source: /dir: Is a directory\n"
+	if output != expected {
+		t.Errorf("expected output %q, got %q", expected, output)
+	}
+}
+
+func TestStatAndTouch(t *testing.T) {
+	shell := NewShell()
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+		&vfs.VFSNode{Name: "dir", IsDir: true, ModTime: modTime, Children: []*vfs.VFSNode{
+			{Name: "a.txt", Content: "hello\n", Owner: "bob", Group: "staff", Mode: 0o600, ModTime: modTime},
+			{Name: "sub", IsDir: true, ModTime: modTime},
+		}},
+	)
+	output := captureOutput(func() {
+		shell.runInput("stat -c '%n %s %F %a %A %U:%G %h %Y %X' /dir/a.txt dir; stat --format=%w /dir/a.txt")
+	})
+	expected := fmt.Sprintf("/dir/a.txt 6 regular file 600 -rw------- bob:staff 1 %d %d\n", modTime.Unix(), modTime.Unix()) +
+		fmt.Sprintf("dir 0 directory 755 drwxr-xr-x -:- 3 %d %d\n", modTime.Unix(), modTime.Unix()) +
+		"-\n"
+	if output != expected {
+		t.Errorf("expected output %q, got %q", expected, output)
+	}
+
+	output = captureOutput(func() {
+		shell.runInput("stat /dir/a.txt")
+	})
+	for _, line := range []string{"  File: /dir/a.txt", "Access: (0600/-rw-------)  Uid: (bob)  Gid: (staff)", "Modify: 2024-01-02 03:04:05.000000000 +0000", " Birth: -"} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("expected stat output to contain %q, got:\n%s", line, output)
+		}
+	}
+
+	output = captureOutput(func() {
+		shell.runInput("touch -d '2020-05-06 07:08:09' /dir/a.txt /dir/new.txt; touch -m -r /dir/new.txt /dir/sub; touch -a -d @0 /dir/sub")
+	})
+	if output != "" {
+		t.Errorf("expected no output from touch, got %q", output)
+	}
+	date := time.Date(2020, 5, 6, 7, 8, 9, 0, time.Local)
+	for _, name := range []string{"/dir/a.txt", "/dir/new.txt"} {
+		st, err := shell.vfs.StatPath(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !st.MTime.Equal(date) || !st.ATime.Equal(date) || !st.CTime.After(date) {
+			t.Errorf("%s: unexpected times atime %v, mtime %v, ctime %v", name, st.ATime, st.MTime, st.CTime)
+		}
+	}
+	st, _ := shell.vfs.StatPath("/dir/sub")
+	if !st.MTime.Equal(date) || st.ATime.Unix() != 0 {
+		t.Errorf("/dir/sub: unexpected times atime %v, mtime %v", st.ATime, st.MTime)
+	}
+	if a, _ := shell.vfs.StatPath("/dir/a.txt"); a.Inode == st.Inode || a.Inode == 0 {
+		t.Errorf("expected distinct inode numbers, got %d and %d", a.Inode, st.Inode)
+	}
+
+	output = captureOutput(func() {
+		shell.runInput("touch -c /dir/none; touch /nodir/x; touch -d yesterday /dir/a.txt; stat /dir/none")
+	})
+	expected = "touch: /nodir/x: No such file or directory\n" +
+		"touch: invalid date format 'yesterday'\n" +
+		"stat: /dir/none: No such file or directory\n"
+	if output != expected {
+		t.Errorf("expected output %q, got %q", expected, output)
+	}
+}
+
+func TestFindCommand(t *testing.T) {
+	shell := NewShell()
+	old := time.Now().Add(-72 * time.Hour)
+	shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+		&vfs.VFSNode{Name: "src", IsDir: true, ModTime: old, Children: []*vfs.VFSNode{
+			{Name: "main.go", Content: strings.Repeat("x", 600), Owner: "bob", ModTime: old},
+			{Name: "README.md", Content: "readme\n", ModTime: time.Now()},
+			{Name: "lib", IsDir: true, ModTime: old, Children: []*vfs.VFSNode{
+				{Name: "util.GO", Content: "package lib\n", Owner: "bob", ModTime: old},
+			}},
+		}},
+	)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"find /src", "/src\n/src/main.go\n/src/README.md\n/src/lib\n/src/lib/util.GO\n"},
+		{"cd /src; find . -maxdepth 1 -type d", ".\n./lib\n"},
+		{"find /src -name '*.go'", "/src/main.go\n"},
+		{"find /src -iname '*.go' -user bob", "/src/main.go\n/src/lib/util.GO\n"},
+		{"find /src -type f -name '[!mR]*'", "/src/lib/util.GO\n"},
+		{"find /src -iname '[r]*'", "/src/README.md\n"},
+		{"find /src -name 'main[' ; echo $?", "0\n"},
+		{"find /src -type f -size +1", "/src/main.go\n"},
+		{"find /src -type f -size -100c", "/src/README.md\n/src/lib/util.GO\n"},
+		{"find /src -type f -mtime +2", "/src/main.go\n/src/lib/util.GO\n"},
+		{"find /src -type f -mtime -1", "/src/README.md\n"},
+		{"find /src -newer /src/main.go", "/src/README.md\n"},
+		{"find /src -mindepth 2", "/src/lib/util.GO\n"},
+		{"find /src ! -type d \\( -name '*.md' -o -name '*.GO' \\)", "/src/README.md\n/src/lib/util.GO\n"},
+		{"find /src/lib -print0", "/src/lib\x00/src/lib/util.GO\x00"},
+		{"find /src -name '*.md' -exec wc -l {} \\;", "1 /src/README.md\n"},
+		{"find /src -type f -exec test -s {} ';' -name 'R*' -print", "/src/README.md\n"},
+	}
+	for _, tt := range tests {
+		shell.currentPath = "/"
+		output := captureOutput(func() {
+			shell.runInput(tt.input)
+		})
+		if output != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, output)
+		}
+	}
+
+	output := captureOutput(func() {
+		shell.runInput("find /src/lib -delete; find /src -type d; find /src -bogus; find /missing -exec echo {} ';'")
+	})
+	expected := "/src\n" +
+		"find: unknown predicate '-bogus'\n" +
+		"find: /missing: No such file or directory\n"
+	if output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+	if shell.status != 1 {
+		t.Errorf("expected status 1, got %d", shell.status)
+	}
+}
+
+func TestTreeDuDf(t *testing.T) {
+	shell := NewShell()
+	shell.vfs.Limits = vfs.Limits{MaxBytes: 4096, MaxNodes: 10}
+	shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+		&vfs.VFSNode{Name: "etc", IsDir: true, ModTime: time.Now(), Children: []*vfs.VFSNode{
+			{Name: "passwd", Content: strings.Repeat("x", 1500), Owner: "root", ModTime: time.Now()},
+			{Name: ".hidden", Content: "h", ModTime: time.Now()},
+			{Name: "conf.d", IsDir: true, ModTime: time.Now(), Children: []*vfs.VFSNode{
+				{Name: "a.conf", Content: strings.Repeat("y", 100), Owner: "bob", ModTime: time.Now()},
+			}},
+		}},
+	)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"tree /etc", "/etc\n├── conf.d\n│   └── a.conf\n└── passwd\n\n1 directory, 2 files\n"},
+		{"tree -a -L 1 /etc", "/etc\n├── .hidden\n├── conf.d\n└── passwd\n\n1 directory, 2 files\n"},
+		{"cd /etc; tree -d", ".\n└── conf.d\n\n1 directory\n"},
+		{"tree -su /etc/conf.d", "[-                  0]  /etc/conf.d\n└── [bob              100]  a.conf\n\n0 directories, 1 file\n"},
+		{"du /etc", "1\t/etc/conf.d\n2\t/etc\n"},
+		{"cd /etc; du -ab", "1500\t./passwd\n1\t./.hidden\n100\t./conf.d/a.conf\n100\t./conf.d\n1601\t.\n"},
+		{"du -sh /etc /etc/passwd", "1.6K\t/etc\n1.5K\t/etc/passwd\n"},
+		{"du --max-depth=0 -b /", "1601\t/\n"},
+		{"df", "Filesystem  1K-blocks       Used  Available  Use% Mounted on\nvfs                 4          2          3   40% /\n"},
+		{"df -i", "Filesystem     Inodes      IUsed      IFree IUse% Mounted on\nvfs                10          6          4   60% /\n"},
+	}
+	for _, tt := range tests {
+		shell.currentPath = "/"
+		output := captureOutput(func() {
+			shell.runInput(tt.input)
+		})
+		if output != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, output)
+		}
+	}
+
+	shell.vfs.Limits = vfs.Limits{}
+	output := captureOutput(func() {
+		shell.runInput("df -h")
+	})
+	expected := "Filesystem       Size       Used      Avail  Use% Mounted on\nvfs                 -       1.6K          -     - /\n"
+	if output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+
+	for value, expected := range map[string]int64{"512": 512, "10K": 10240, "1.5M": 1572864, "2GiB": 2 << 30, "3kb": 3072} {
+		if size, err := parseSize(value); err != nil || size != expected {
+			t.Errorf("parseSize(%q): expected %d, got %d (%v)", value, expected, size, err)
+		}
+	}
+	if _, err := parseSize("ten"); err == nil {
+		t.Errorf("expected an error for an invalid size")
+	}
+	for size, expected := range map[int64]string{0: "0", 1023: "1023", 1024: "1.0K", 1536: "1.5K", 10 * 1024: "10K", 1<<20 - 1: "1.0M", 5 << 30: "5.0G"} {
+		if human := humanSize(size); human != expected {
+			t.Errorf("humanSize(%d): expected %q, got %q", size, expected, human)
+		}
+	}
+}
+
+func TestSortUniq(t *testing.T) {
+	shell := NewShell()
+	shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+		&vfs.VFSNode{Name: "words", Content: "b\na\nb\n\n\nA\na\nb\n", ModTime: time.Now()},
+		&vfs.VFSNode{Name: "scores", Content: "bob:10\nann:9\ncid:100\nann:9\n", ModTime: time.Now()},
+	)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Повторы, стоящие рядом; пустые строки сохраняются
+		{"uniq /words", "b\na\nb\n\nA\na\nb\n"},
+		{"uniq -i /words", "b\na\nb\n\nA\nb\n"},
+		{"uniq -d /words", "\n"},
+		{"uniq -u -i /words", "b\na\nb\nb\n"},
+		{"sort /words | uniq -c", "      2 \n      1 A\n      2 a\n      3 b\n"},
+		{"sort -u /words", "\nA\na\nb\n"},
+		{"sort -t : -k 2n /scores", "ann:9\nann:9\nbob:10\ncid:100\n"},
+		{"sort -t : -k 2 -r /scores", "ann:9\nann:9\ncid:100\nbob:10\n"},
+		{"sort -n -r -t : -k 2 /scores | uniq", "cid:100\nbob:10\nann:9\n"},
+		{"echo 'x 1 a\nx 2 a\ny 3 b' | uniq -f 2", "x 1 a\ny 3 b\n"},
+		{"echo 'x1\ny1\nz2' | uniq -s 1 -c", "      2 x1\n      1 z2\n"},
+		{"uniq /words /out; sort -o /sorted /scores; uniq /out; uniq /sorted", "b\na\nb\n\nA\na\nb\nann:9\nbob:10\ncid:100\n"},
+		{"uniq -f x /words", "uniq: x: invalid number\n"},
+		{"sort -k 0 /words", "sort: invalid field specification '0'\n"},
+	}
+	for _, tt := range tests {
+		output := captureOutput(func() { shell.runInput(tt.input) })
+		if output != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, output)
+		}
+	}
+}
+
+func TestTextCommands(t *testing.T) {
+	shell := NewShell()
+	shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+		&vfs.VFSNode{Name: "app.conf", Content: "# app\nhost=localhost\nport=8080\nuser=admin\n", ModTime: time.Now()},
+		&vfs.VFSNode{Name: "sales", Content: "ann 10 north\nbob 25 south\ncid 5 north\n", ModTime: time.Now()},
+		&vfs.VFSNode{Name: "nonl", Content: "a\nb", ModTime: time.Now()},
+	)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"sed 's/=/: /' /app.conf", "# app\nhost: localhost\nport: 8080\nuser: admin\n"},
+		{"sed -n '/port/p;$=' /app.conf", "port=8080\n4\n"},
+		{"sed '/^#/d;s/\\(.*\\)=\\(.*\\)/\\2 <- \\1/' /app.conf", "localhost <- host\n8080 <- port\nadmin <- user\n"},
+		{"sed -E 's/(o+)/[\\1]/2' /app.conf", "# app\nhost=l[o]calhost\nport=8080\nuser=admin\n"},
+		{"sed '2,3!d' /app.conf", "host=localhost\nport=8080\n"},
+		{"sed -n '/host/,/port/p' /app.conf", "host=localhost\nport=8080\n"},
+		{"echo abc | sed 's/x*/-/g'", "-a-b-c-\n"},
+		{"sed -i 's/8080/9090/' /app.conf; sed -n 3p /app.conf", "port=9090\n"},
+		{"sed 2q /app.conf", "# app\nhost=localhost\n"},
+		{"sed p /nonl; echo; sed -n '$=' /nonl /app.conf", "a\na\nb\nb\n6\n"},
+		{"sed -i s/b/c/ /nonl; wc -c /nonl", "3 /nonl\n"},
+		{"echo x | sed -i p -; echo $?", "sed: couldn't edit -: not a regular file\n4\n"},
+		{"sed 'k' /app.conf", "sed: -e expression #1, char 1: unknown command: 'k'\n"},
+		{"cut -d = -f 2 -s /app.conf", "localhost\n9090\nadmin\n"},
+		{"cut -c 1-3,5- /app.conf", "# ap\nhos=localhost\npor=9090\nuse=admin\n"},
+		{"echo 'a:b:c:d' | cut -d : -f -2,4", "a:b:d\n"},
+		{"cut /app.conf", "cut: you must specify a list of characters or fields\n"},
+		{"echo 'Hello World' | tr a-z A-Z", "HELLO WORLD\n"},
+		{"echo 'Hello   World' | tr -s ' ' _", "Hello_World\n"},
+		{"echo 'a1b22c333' | tr -d '[:digit:]'", "abc\n"},
+		{"echo 'aabbcc' | tr -s a-c xy", "xy\n"},
+		{"tr a", "tr: missing operand after 'a'\n"},
+		{"awk '{print $1, $2 * 2}' /sales", "ann 20\nbob 50\ncid 10\n"},
+		{"awk '$3 == \"north\" && $2 > 6' /sales", "ann 10 north\n"},
+		{"awk '/north/ {printf \"%s:%d\\n\", $1, $2 + 1} END {print NR, FILENAME}' /sales", "ann:11\ncid:6\n3 /sales\n"},
+		{"awk -F = -v OFS=: 'NR > 1 {print $2, $1, NF}' /app.conf", "localhost:host:2\n9090:port:2\nadmin:user:2\n"},
+		{"echo 'a b c' | awk '{print $NF $1, $2 \"-\" $3, $1 ~ /^a/, NF % 2}'", "ca b-c 1 1\n"},
+		{"echo | awk 'BEGIN {printf \"%s %d %.2f\\n\", \"x\", 7 / 2, 1 / 4} END {print NR}'", "x 3 0.25\n1\n"},
+		{"awk '{n++}' /sales", "awk: syntax error near 'n'\n"},
+		{"awk 'BEGIN {print 1/0}'", "awk: division by zero\n"},
+		{"awk '{print $1 > \"out\"}' /sales", "awk: output redirection is not supported\n"},
+	}
+	for _, tt := range tests {
+		output := captureOutput(func() { shell.runInput(tt.input) })
+		if output != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, output)
+		}
+	}
+}
+
+func TestTextStreaming(t *testing.T) {
+	// Вход во много раз больше буфера чтения и содержит строку длиннее буфера
+	var content, expected strings.Builder
+	for i := range 20000 {
+		fmt.Fprintf(&content, "key%d,value%d,tail\n", i, i)
+		fmt.Fprintf(&expected, "VALUE%d\n", i)
+	}
+	long := strings.Repeat("x", 10000)
+	content.WriteString("long," + long + "\n")
+	expected.WriteString(strings.ToUpper(long) + "\n")
+	shell := NewShell()
+	shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+		&vfs.VFSNode{Name: "big.csv", Content: content.String(), ModTime: time.Now()},
+	)
+	output := captureOutput(func() { shell.runInput("cut -d , -f 2 /big.csv | tr a-z A-Z") })
+	if output != expected.String() {
+		t.Errorf("cut | tr: output of %d bytes differs from the expected %d bytes", len(output), expected.Len())
+	}
+	// tr -d удаляет переводы строк на границах всех прочитанных строк
+	output = captureOutput(func() { shell.runInput("cut -d , -f 3 /big.csv | tr -d '\\n' | wc -c") })
+	if output != "80000\n" {
+		t.Errorf("cut | tr -d: expected %q, got %q", "80000\n", output)
+	}
+}
+
+func TestQuotaCommand(t *testing.T) {
+	shell := NewShell()
+	limits, err := parseLimits("1M", "0", "bob=2K, alice=1K")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits.MaxBytes != 1<<20 || limits.MaxFileSize != 0 || !reflect.DeepEqual(limits.OwnerQuotas, map[string]int64{"bob": 2048, "alice": 1024}) {
+		t.Errorf("unexpected limits %+v", limits)
+	}
+	if _, err := parseLimits("0", "0", "bob"); err == nil {
+		t.Errorf("expected an error for a malformed -user-quota")
+	}
+	shell.vfs.Limits = limits
+	shell.vfs.Root.Children = append(shell.vfs.Root.Children,
+		&vfs.VFSNode{Name: "home", IsDir: true, ModTime: time.Now(), Children: []*vfs.VFSNode{
+			{Name: "big", Content: strings.Repeat("x", 1500), Owner: "bob", ModTime: time.Now()},
+			{Name: "small", Content: "hi", Owner: "carol", ModTime: time.Now()},
+		}},
+	)
+	output := captureOutput(func() {
+		shell.runInput("quota; chown alice /home/big; chown bob /home/small; quota -h carol bob")
+	})
+	expected := "Owner              Used      Quota   Files\n" +
+		"alice                 0          1       0\n" +
+		"bob                   2          2       1\n" +
+		"carol                 1          -       1\n" +
+		"chown: /home/big: Disk quota exceeded\n" +
+		"Changed owner of '/home/small' to 'bob'\n" +
+		"Owner of file is bob\n" +
+		"Owner              Used      Quota   Files\n" +
+		"carol                 0          -       0\n" +
+		"bob                1.5K       2.0K       2\n"
+	if output != expected {
+		t.Errorf("expected output %q, got %q", expected, output)
+	}
+}
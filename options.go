@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ошибка разбора опций команды
+type OptionError struct {
+	Cmd string
+	Msg string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Cmd, e.Msg)
+}
+
+// Результат разбора опций. Значения доступны как по короткому, так и по длинному имени
+type Options struct {
+	values map[string][]string
+}
+
+// Указан ли флаг
+func (o *Options) Has(name string) bool {
+	_, ok := o.values[name]
+	return ok
+}
+
+// Последнее значение флага или пустая строка
+func (o *Options) Value(name string) string {
+	vals := o.values[name]
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[len(vals)-1]
+}
+
+// Все значения флага в порядке указания
+func (o *Options) Values(name string) []string {
+	return o.values[name]
+}
+
+func (o *Options) set(spec FlagSpec, value string) {
+	for _, key := range []string{spec.Short, spec.Long} {
+		if key != "" {
+			o.values[key] = append(o.values[key], value)
+		}
+	}
+}
+
+// Разбирает аргументы в стиле POSIX/GNU: объединенные короткие флаги (-rf),
+// значения слитно и раздельно (-n5, -n 5, --lines=5, --lines 5), -- как конец опций.
+// Опции могут стоять в любом месте, остальные аргументы возвращаются как операнды.
+// При stopAtOperand первый операнд, как и --, завершает разбор опций
+func parseOptions(cmd string, specs []FlagSpec, args []string, stopAtOperand bool) (*Options, []string, error) {
+	opts := &Options{values: map[string][]string{}}
+	var operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			operands = append(operands, args[i+1:]...)
+			return opts, operands, nil
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			spec, err := findLongFlag(cmd, specs, name)
+			if err != nil {
+				return nil, nil, err
+			}
+			if spec.Arg == "" {
+				if hasValue {
+					return nil, nil, &OptionError{cmd, fmt.Sprintf("option '--%s' doesn't allow an argument", spec.Long)}
+				}
+				opts.set(spec, "")
+				continue
+			}
+			if !hasValue {
+				if i+1 >= len(args) {
+					return nil, nil, &OptionError{cmd, fmt.Sprintf("option '--%s' requires an argument", spec.Long)}
+				}
+				i++
+				value = args[i]
+			}
+			opts.set(spec, value)
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			// Группа коротких флагов, например -rf или -n5
+			for j := 1; j < len(arg); j++ {
+				name := arg[j : j+1]
+				spec, ok := findShortFlag(specs, name)
+				if !ok {
+					return nil, nil, &OptionError{cmd, fmt.Sprintf("invalid option -- '%s'", name)}
+				}
+				if spec.Arg == "" {
+					opts.set(spec, "")
+					continue
+				}
+				value := arg[j+1:]
+				if value == "" {
+					if i+1 >= len(args) {
+						return nil, nil, &OptionError{cmd, fmt.Sprintf("option requires an argument -- '%s'", name)}
+					}
+					i++
+					value = args[i]
+				}
+				opts.set(spec, value)
+				break
+			}
+		case stopAtOperand:
+			operands = append(operands, args[i:]...)
+			return opts, operands, nil
+		default:
+			operands = append(operands, arg)
+		}
+	}
+	return opts, operands, nil
+}
+
+func findShortFlag(specs []FlagSpec, name string) (FlagSpec, bool) {
+	for _, spec := range specs {
+		if spec.Short == name {
+			return spec, true
+		}
+	}
+	return FlagSpec{}, false
+}
+
+// Ищет длинный флаг по имени или по однозначному префиксу
+func findLongFlag(cmd string, specs []FlagSpec, name string) (FlagSpec, error) {
+	var matches []FlagSpec
+	for _, spec := range specs {
+		if spec.Long == "" {
+			continue
+		}
+		if spec.Long == name {
+			return spec, nil
+		}
+		if strings.HasPrefix(spec.Long, name) {
+			matches = append(matches, spec)
+		}
+	}
+	if len(matches) == 1 && name != "" {
+		return matches[0], nil
+	}
+	if len(matches) > 1 && name != "" {
+		return FlagSpec{}, &OptionError{cmd, fmt.Sprintf("option '--%s' is ambiguous", name)}
+	}
+	return FlagSpec{}, &OptionError{cmd, fmt.Sprintf("unrecognized option '--%s'", name)}
+}
+
+// Разбирает аргументы по флагам зарегистрированной команды.
+// При ошибке выводит сообщение и возвращает ok == false
+func (s *Shell) parseArgs(cmd string, args []string) (opts *Options, operands []string, ok bool) {
+	var info CommandInfo
+	if command, exists := s.commands[cmd]; exists {
+		info = command.Info()
+	}
+	opts, operands, err := parseOptions(cmd, info.Flags, args, info.StopAtOperand)
+	if err != nil {
+		s.errorf("%v\n", err)
+		fmt.Fprintf(s.errOut(), "Try '%s --help' for more information.\n", cmd)
+		s.status = 2
+		return nil, nil, false
+	}
+	return opts, operands, true
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/TimofeyChernyshev/MIREA-Configuration-management-1/vfs"
+)
+
+// Форматы вывода результатов команд
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// Результат выполнения команды в режиме JSON. Выводится одной строкой на команду
+type commandResult struct {
+	Command string         `json:"command"`
+	Args    []string       `json:"args"`
+	Status  int            `json:"status"`
+	Results []any          `json:"results,omitempty"` // структурированные результаты команды
+	Output  string         `json:"output,omitempty"`  // текстовый вывод команд без структурированных результатов
+	Errors  []commandError `json:"errors,omitempty"`
+	out     *bytes.Buffer  // перехваченный вывод команды
+	errs    *bytes.Buffer  // перехваченные сообщения об ошибках, еще не добавленные в Errors
+}
+
+// Ошибка команды в режиме JSON. Для ошибок VFS кроме сообщения заполняются
+// код в стиле errno, путь и причина
+type commandError struct {
+	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`   // например "ENOENT"
+	Path    string `json:"path,omitempty"`   // путь, при обращении к которому возникла ошибка
+	Reason  string `json:"reason,omitempty"` // например "No such file or directory"
+}
+
+// Коды ошибок VFS в стиле errno. Ошибки io/fs в конце списка - для ошибок
+// файлов на диске (vfs-save, скрипты с диска)
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{vfs.ErrNotExist, "ENOENT"},
+	{vfs.ErrExist, "EEXIST"},
+	{vfs.ErrPermission, "EACCES"},
+	{vfs.ErrNotDir, "ENOTDIR"},
+	{vfs.ErrIsDir, "EISDIR"},
+	{vfs.ErrNotEmpty, "ENOTEMPTY"},
+	{vfs.ErrNoSpace, "ENOSPC"},
+	{vfs.ErrQuota, "EDQUOT"},
+	{vfs.ErrFileTooLarge, "EFBIG"},
+	{vfs.ErrNameTooLong, "ENAMETOOLONG"},
+	{fs.ErrNotExist, "ENOENT"},
+	{fs.ErrExist, "EEXIST"},
+	{fs.ErrPermission, "EACCES"},
+}
+
+// Запись об ошибке: код и причина определяются по виду ошибки VFS, путь - по
+// *fs.PathError или, если его нет, по пути, указанному команде
+func newCommandError(message, path string, err error) commandError {
+	e := commandError{Message: message, Path: path}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		e.Path = pathErr.Path
+	}
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			e.Code = c.code
+			e.Reason = vfs.Reason(err).Error()
+			break
+		}
+	}
+	return e
+}
+
+// Переносит накопленные текстовые сообщения об ошибках в Errors, по одному на строку
+func (r *commandResult) flushErrors() {
+	for _, line := range strings.Split(r.errs.String(), "\n") {
+		if line != "" {
+			r.Errors = append(r.Errors, commandError{Message: line})
+		}
+	}
+	r.errs.Reset()
+}
+
+// Добавляет ошибку в запись выполняемой команды, если ее ошибки перехватываются.
+// Возвращает false, если ошибку нужно вывести текстом
+func (s *Shell) recordError(e commandError) bool {
+	if s.record == nil || s.stderr != s.record.errs {
+		return false
+	}
+	s.record.flushErrors()
+	s.record.Errors = append(s.record.Errors, e)
+	return true
+}
+
+// Записи структурированных результатов команд
+type (
+	lsEntry struct {
+		Path    string    `json:"path"` // просматриваемый каталог
+		Name    string    `json:"name"`
+		Type    string    `json:"type"` // "file" или "dir"
+		Size    int       `json:"size"`
+		Owner   string    `json:"owner,omitempty"`
+		ModTime time.Time `json:"modTime"`
+	}
+	cdResult struct {
+		Path string `json:"path"`
+	}
+	mvResult struct {
+		Source      string `json:"source"`
+		Destination string `json:"destination"`
+	}
+	chownResult struct {
+		File  string `json:"file"`
+		Owner string `json:"owner"`
+	}
+	linesResult struct {
+		File  string   `json:"file,omitempty"`
+		Lines []string `json:"lines"`
+	}
+	wcResult struct {
+		File  string `json:"file,omitempty"`
+		Lines int    `json:"lines"`
+		Words int    `json:"words"`
+		Bytes int    `json:"bytes"`
+	}
+	saveResult struct {
+		Path string `json:"path"`
+	}
+	findResult struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}
+	treeEntry struct {
+		Name     string      `json:"name"`
+		Type     string      `json:"type"`
+		Size     int         `json:"size"`
+		Owner    string      `json:"owner,omitempty"`
+		Children []treeEntry `json:"children,omitempty"`
+	}
+	duResult struct {
+		Path  string `json:"path"`
+		Bytes int64  `json:"bytes"`
+	}
+	dfResult struct {
+		Filesystem string `json:"filesystem"`
+		Bytes      int64  `json:"bytes"`
+		MaxBytes   int64  `json:"maxBytes"` // 0 - без ограничения
+		Nodes      int    `json:"nodes"`
+		MaxNodes   int    `json:"maxNodes"` // 0 - без ограничения
+		MountedOn  string `json:"mountedOn"`
+	}
+	quotaResult struct {
+		Owner string `json:"owner"`
+		Bytes int64  `json:"bytes"`
+		Quota int64  `json:"quota,omitempty"` // 0 - без квоты
+		Files int    `json:"files"`
+	}
+	statResult struct {
+		Path      string    `json:"path"`
+		Type      string    `json:"type"`
+		Size      int64     `json:"size"`
+		Mode      string    `json:"mode"` // права в восьмеричном виде, например "0644"
+		Owner     string    `json:"owner,omitempty"`
+		Group     string    `json:"group,omitempty"`
+		Inode     uint64    `json:"inode"`
+		Links     int       `json:"links"`
+		ATime     time.Time `json:"atime"`
+		MTime     time.Time `json:"mtime"`
+		CTime     time.Time `json:"ctime"`
+		BirthTime time.Time `json:"birthTime,omitzero"`
+	}
+)
+
+func nodeType(isDir bool) string {
+	if isDir {
+		return "dir"
+	}
+	return "file"
+}
+
+// Добавляет структурированный результат к выполняемой команде.
+// Возвращает false, если команда должна вывести результат текстом:
+// режим JSON выключен или вывод команды перенаправлен (конвейер, подстановка)
+func (s *Shell) emit(result any) bool {
+	if !s.recording() {
+		return false
+	}
+	s.record.Results = append(s.record.Results, result)
+	return true
+}
+
+// Собираются ли структурированные результаты текущей команды
+func (s *Shell) recording() bool {
+	return s.record != nil && s.stdout == s.record.out
+}
+
+// Выводится ли результат команды в формате JSON
+func (s *Shell) jsonOutput() bool {
+	return s.outputFormat == outputJSON && s.stdout == nil
+}
+
+// Выполняет команду, перехватывая ее вывод и ошибки, и выводит результат одной строкой JSON
+func (s *Shell) runRecorded(name string, args []string, run func() error) {
+	var out, errs bytes.Buffer
+	record := &commandResult{Command: name, Args: args, out: &out, errs: &errs}
+	if record.Args == nil {
+		record.Args = []string{}
+	}
+	savedRecord, savedOut, savedErr := s.record, s.stdout, s.stderr
+	s.record, s.stdout, s.stderr = record, &out, &errs
+	err := run()
+	s.record, s.stdout, s.stderr = savedRecord, savedOut, savedErr
+	record.flushErrors()
+	if err != nil {
+		record.Errors = append(record.Errors, newCommandError(fmt.Sprintf("Error: %v", err), "", err))
+	}
+	record.Status = s.status
+	record.Output = out.String()
+	data, _ := json.Marshal(record)
+	fmt.Fprintf(s.out(), "%s\n", data)
+}
+
+// Сообщает об ошибке разбора команды: текстом или записью JSON
+func (s *Shell) syntaxError(err error) {
+	if s.jsonOutput() {
+		s.runRecorded("", nil, func() error {
+			s.status = 2
+			return err
+		})
+		return
+	}
+	s.errorf("Error: %v\n", err)
+	s.status = 2
+}
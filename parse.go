@@ -0,0 +1,600 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Узлы синтаксического дерева. Слова хранятся в исходном виде (с кавычками),
+// раскрытие переменных и шаблонов выполняется при исполнении
+
+// Последовательность команд, разделенных ; или переводом строки
+type commandList []commandNode
+
+type commandNode interface{}
+
+// Простая команда: присваивания и слова
+type simpleCommand struct {
+	assigns []string // NAME=value
+	words   []string
+}
+
+// Цепочка команд, связанных && и ||
+type andOrList struct {
+	first commandNode
+	rest  []andOrItem
+}
+
+type andOrItem struct {
+	op  string // "&&" или "||"
+	cmd commandNode
+}
+
+// ! command - инверсия статуса
+type negation struct {
+	cmd commandNode
+}
+
+// cmd & - команда, выполняемая в фоне
+type backgroundJob struct {
+	cmd commandNode
+}
+
+// cmd1 | cmd2 | ... - вывод каждой команды передается на вход следующей
+type pipeline struct {
+	cmds []commandNode
+}
+
+// if/then/elif/else/fi
+type ifClause struct {
+	conds    []commandList // условия if и elif
+	bodies   []commandList // соответствующие им ветки then
+	elseBody commandList
+}
+
+// for NAME in WORDS; do BODY; done
+type forClause struct {
+	name  string
+	words []string
+	body  commandList
+}
+
+// while/until COND; do BODY; done
+type whileClause struct {
+	cond  commandList
+	body  commandList
+	until bool
+}
+
+// case WORD in PATTERN) BODY;; ... esac
+type caseClause struct {
+	word  string
+	items []caseItem
+}
+
+type caseItem struct {
+	patterns []string
+	body     commandList
+}
+
+// { BODY; }
+type braceGroup struct {
+	body commandList
+}
+
+// Определение функции: NAME() BODY
+type funcDef struct {
+	name string
+	body commandNode
+}
+
+// Ключевые слова, завершающие вложенный список команд
+var listTerminators = map[string]bool{
+	"then": true, "elif": true, "else": true, "fi": true,
+	"do": true, "done": true, "esac": true, "}": true,
+}
+
+// Синтаксический анализатор, строящий дерево по лексемам
+type scriptParser struct {
+	tokens []token
+	pos    int
+}
+
+// Разбирает текст скрипта в список команд
+func parseScript(input string) (commandList, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &scriptParser{tokens: tokens}
+	list, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if tok, ok := p.peek(); ok {
+		return nil, p.unexpected(tok)
+	}
+	return list, nil
+}
+
+func (p *scriptParser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// Является ли следующая лексема словом text
+func (p *scriptParser) peekWord(text string) bool {
+	tok, ok := p.peek()
+	return ok && tok.kind == tokWord && tok.text == text
+}
+
+// Является ли следующая лексема оператором text
+func (p *scriptParser) peekOp(text string) bool {
+	tok, ok := p.peek()
+	return ok && tok.kind == tokOp && tok.text == text
+}
+
+func (p *scriptParser) unexpected(tok token) error {
+	text := tok.text
+	if text == "\n" {
+		text = "newline"
+	}
+	return &SyntaxError{Msg: fmt.Sprintf("unexpected token '%s'", text)}
+}
+
+// Ошибка при неожиданном конце ввода - ввод можно продолжить
+func (p *scriptParser) unexpectedEOF(expected string) error {
+	return &SyntaxError{Msg: fmt.Sprintf("unexpected end of file, expecting '%s'", expected), Incomplete: true}
+}
+
+// Пропускает переводы строк
+func (p *scriptParser) skipNewlines() {
+	for p.peekOp("\n") {
+		p.pos++
+	}
+}
+
+// Ожидает ключевое слово
+func (p *scriptParser) expectWord(text string) error {
+	tok, ok := p.peek()
+	if !ok {
+		return p.unexpectedEOF(text)
+	}
+	if tok.kind != tokWord || tok.text != text {
+		return p.unexpected(tok)
+	}
+	p.pos++
+	return nil
+}
+
+// list := and_or ((';' | '&' | '\n') and_or)* ['&']
+func (p *scriptParser) parseList() (commandList, error) {
+	var list commandList
+	for {
+		for p.peekOp("\n") || p.peekOp(";") {
+			p.pos++
+		}
+		tok, ok := p.peek()
+		if !ok {
+			return list, nil
+		}
+		if tok.kind == tokOp && (tok.text == ";;" || tok.text == ")") {
+			return list, nil
+		}
+		if tok.kind == tokWord && listTerminators[tok.text] {
+			return list, nil
+		}
+		cmd, err := p.parseAndOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peekOp("&") {
+			p.pos++
+			cmd = &backgroundJob{cmd}
+		}
+		list = append(list, cmd)
+		tok, ok = p.peek()
+		if ok && tok.kind == tokOp && tok.text != ";" && tok.text != "\n" && tok.text != ";;" && tok.text != ")" {
+			return nil, p.unexpected(tok)
+		}
+	}
+}
+
+// and_or := pipeline (('&&' | '||') linebreak pipeline)*
+func (p *scriptParser) parseAndOr() (commandNode, error) {
+	first, err := p.parsePipeline()
+	if err != nil {
+		return nil, err
+	}
+	if !p.peekOp("&&") && !p.peekOp("||") {
+		return first, nil
+	}
+	list := &andOrList{first: first}
+	for p.peekOp("&&") || p.peekOp("||") {
+		op := p.tokens[p.pos].text
+		p.pos++
+		p.skipNewlines()
+		if _, ok := p.peek(); !ok {
+			return nil, &SyntaxError{Msg: fmt.Sprintf("unexpected end of file after '%s'", op), Incomplete: true}
+		}
+		cmd, err := p.parsePipeline()
+		if err != nil {
+			return nil, err
+		}
+		list.rest = append(list.rest, andOrItem{op, cmd})
+	}
+	return list, nil
+}
+
+// pipeline := ['!'] command ('|' linebreak command)*
+func (p *scriptParser) parsePipeline() (commandNode, error) {
+	if p.peekWord("!") {
+		p.pos++
+		cmd, err := p.parsePipeline()
+		if err != nil {
+			return nil, err
+		}
+		return &negation{cmd}, nil
+	}
+	first, err := p.parseCommand()
+	if err != nil {
+		return nil, err
+	}
+	if !p.peekOp("|") {
+		return first, nil
+	}
+	pipe := &pipeline{cmds: []commandNode{first}}
+	for p.peekOp("|") {
+		p.pos++
+		p.skipNewlines()
+		if _, ok := p.peek(); !ok {
+			return nil, &SyntaxError{Msg: "unexpected end of file after '|'", Incomplete: true}
+		}
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		pipe.cmds = append(pipe.cmds, cmd)
+	}
+	return pipe, nil
+}
+
+func (p *scriptParser) parseCommand() (commandNode, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, &SyntaxError{Msg: "unexpected end of file", Incomplete: true}
+	}
+	if tok.kind == tokWord {
+		if p.isFuncDef() {
+			return p.parseFuncDef()
+		}
+		switch tok.text {
+		case "function":
+			return p.parseFuncDef()
+		case "if":
+			return p.parseIf()
+		case "for":
+			return p.parseFor()
+		case "while", "until":
+			return p.parseWhile()
+		case "case":
+			return p.parseCase()
+		case "{":
+			return p.parseBrace()
+		}
+	}
+	return p.parseSimple()
+}
+
+func (p *scriptParser) parseSimple() (commandNode, error) {
+	cmd := &simpleCommand{}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokWord {
+			break
+		}
+		p.pos++
+		if len(cmd.words) == 0 && isAssignment(tok.text) {
+			cmd.assigns = append(cmd.assigns, tok.text)
+		} else {
+			cmd.words = append(cmd.words, tok.text)
+		}
+	}
+	if len(cmd.words) == 0 && len(cmd.assigns) == 0 {
+		tok, _ := p.peek()
+		return nil, p.unexpected(tok)
+	}
+	return cmd, nil
+}
+
+// Является ли слово присваиванием NAME=value
+func isAssignment(word string) bool {
+	name, _, found := cutUnquoted(word, '=')
+	return found && isValidName(name)
+}
+
+// Делит слово по первому символу sep, стоящему вне кавычек
+func cutUnquoted(word string, sep byte) (string, string, bool) {
+	for i := 0; i < len(word); i++ {
+		switch word[i] {
+		case '\\', '\'', '"':
+			return "", "", false
+		case sep:
+			return word[:i], word[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+// Допустимое имя переменной: буквы, цифры и _, не начинается с цифры
+func isValidName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Начинается ли с текущей позиции определение функции NAME()
+func (p *scriptParser) isFuncDef() bool {
+	if p.pos+2 >= len(p.tokens) {
+		return false
+	}
+	lparen, rparen := p.tokens[p.pos+1], p.tokens[p.pos+2]
+	return lparen.kind == tokOp && lparen.text == "(" && rparen.kind == tokOp && rparen.text == ")"
+}
+
+// NAME() BODY или function NAME [()] BODY, где BODY - составная команда
+func (p *scriptParser) parseFuncDef() (commandNode, error) {
+	if p.peekWord("function") {
+		p.pos++
+	}
+	tok, ok := p.peek()
+	if !ok {
+		return nil, &SyntaxError{Msg: "unexpected end of file", Incomplete: true}
+	}
+	if tok.kind != tokWord || !isValidFuncName(tok.text) {
+		return nil, &SyntaxError{Msg: fmt.Sprintf("'%s': not a valid function name", tok.text)}
+	}
+	p.pos++
+	if p.peekOp("(") {
+		p.pos++
+		if !p.peekOp(")") {
+			if tok, ok := p.peek(); ok {
+				return nil, p.unexpected(tok)
+			}
+			return nil, p.unexpectedEOF(")")
+		}
+		p.pos++
+	}
+	p.skipNewlines()
+	next, ok := p.peek()
+	if !ok {
+		return nil, p.unexpectedEOF("{")
+	}
+	if next.kind != tokWord || !isCompoundStart(next.text) {
+		return nil, p.unexpected(next)
+	}
+	body, err := p.parseCommand()
+	if err != nil {
+		return nil, err
+	}
+	return &funcDef{name: tok.text, body: body}, nil
+}
+
+// Начинает ли слово составную команду
+func isCompoundStart(word string) bool {
+	switch word {
+	case "{", "if", "for", "while", "until", "case":
+		return true
+	}
+	return false
+}
+
+// Допустимое имя функции: как имя переменной, но также допускаются - и .
+func isValidFuncName(name string) bool {
+	return isValidName(strings.NewReplacer("-", "_", ".", "_").Replace(name)) && !listTerminators[name]
+}
+
+func (p *scriptParser) parseIf() (commandNode, error) {
+	p.pos++ // if
+	clause := &ifClause{}
+	for {
+		cond, err := p.parseCondition("then")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectWord("then"); err != nil {
+			return nil, err
+		}
+		body, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		clause.conds = append(clause.conds, cond)
+		clause.bodies = append(clause.bodies, body)
+		if p.peekWord("elif") {
+			p.pos++
+			continue
+		}
+		break
+	}
+	if p.peekWord("else") {
+		p.pos++
+		body, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		clause.elseBody = body
+	}
+	if err := p.expectWord("fi"); err != nil {
+		return nil, err
+	}
+	return clause, nil
+}
+
+// Разбирает непустой список команд условия, за которым следует ключевое слово expected
+func (p *scriptParser) parseCondition(expected string) (commandList, error) {
+	cond, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if len(cond) == 0 {
+		if tok, ok := p.peek(); ok {
+			return nil, p.unexpected(tok)
+		}
+		return nil, p.unexpectedEOF(expected)
+	}
+	return cond, nil
+}
+
+func (p *scriptParser) parseFor() (commandNode, error) {
+	p.pos++ // for
+	tok, ok := p.peek()
+	if !ok {
+		return nil, p.unexpectedEOF("in")
+	}
+	if tok.kind != tokWord || !isValidName(tok.text) {
+		return nil, &SyntaxError{Msg: fmt.Sprintf("'%s' is not a valid identifier", tok.text)}
+	}
+	p.pos++
+	clause := &forClause{name: tok.text}
+	p.skipNewlines()
+	if p.peekWord("in") {
+		p.pos++
+		for {
+			tok, ok := p.peek()
+			if !ok || tok.kind != tokWord {
+				break
+			}
+			clause.words = append(clause.words, tok.text)
+			p.pos++
+		}
+	} else {
+		// for NAME; do - перебор позиционных параметров
+		clause.words = []string{`"$@"`}
+	}
+	if p.peekOp(";") {
+		p.pos++
+	}
+	p.skipNewlines()
+	if err := p.expectWord("do"); err != nil {
+		return nil, err
+	}
+	body, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectWord("done"); err != nil {
+		return nil, err
+	}
+	clause.body = body
+	return clause, nil
+}
+
+func (p *scriptParser) parseWhile() (commandNode, error) {
+	clause := &whileClause{until: p.tokens[p.pos].text == "until"}
+	p.pos++
+	cond, err := p.parseCondition("do")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectWord("do"); err != nil {
+		return nil, err
+	}
+	body, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectWord("done"); err != nil {
+		return nil, err
+	}
+	clause.cond = cond
+	clause.body = body
+	return clause, nil
+}
+
+func (p *scriptParser) parseCase() (commandNode, error) {
+	p.pos++ // case
+	tok, ok := p.peek()
+	if !ok {
+		return nil, p.unexpectedEOF("in")
+	}
+	if tok.kind != tokWord {
+		return nil, p.unexpected(tok)
+	}
+	p.pos++
+	clause := &caseClause{word: tok.text}
+	p.skipNewlines()
+	if err := p.expectWord("in"); err != nil {
+		return nil, err
+	}
+	for {
+		p.skipNewlines()
+		if p.peekWord("esac") {
+			p.pos++
+			return clause, nil
+		}
+		if p.peekOp("(") {
+			p.pos++
+		}
+		// Шаблоны, разделенные |
+		var item caseItem
+		for {
+			tok, ok := p.peek()
+			if !ok {
+				return nil, p.unexpectedEOF(")")
+			}
+			if tok.kind != tokWord {
+				return nil, p.unexpected(tok)
+			}
+			item.patterns = append(item.patterns, tok.text)
+			p.pos++
+			if !p.peekOp("|") {
+				break
+			}
+			p.pos++
+		}
+		tok, ok := p.peek()
+		if !ok {
+			return nil, p.unexpectedEOF(")")
+		}
+		if tok.kind != tokOp || tok.text != ")" {
+			return nil, p.unexpected(tok)
+		}
+		p.pos++
+		body, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		item.body = body
+		clause.items = append(clause.items, item)
+		if p.peekOp(";;") {
+			p.pos++
+			continue
+		}
+		p.skipNewlines()
+		if err := p.expectWord("esac"); err != nil {
+			return nil, err
+		}
+		return clause, nil
+	}
+}
+
+func (p *scriptParser) parseBrace() (commandNode, error) {
+	p.pos++ // {
+	body, err := p.parseCondition("}")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectWord("}"); err != nil {
+		return nil, err
+	}
+	return &braceGroup{body}, nil
+}
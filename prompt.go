@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Приглашение по умолчанию: user@host:path$
+const defaultPrompt = `\u@\h:\w\$ `
+
+// Имя оболочки для \s
+const shellName = "vfs-shell"
+
+// Имена пользователя и хоста запрашиваются у системы один раз
+var hostInfo struct {
+	once     sync.Once
+	username string
+	hostname string
+}
+
+func lookupHostInfo() (string, string) {
+	hostInfo.once.Do(func() {
+		if currentUser, err := user.Current(); err == nil {
+			// В Windows имя имеет вид DOMAIN\user
+			hostInfo.username = currentUser.Username[strings.LastIndex(currentUser.Username, `\`)+1:]
+		} else {
+			hostInfo.username = os.Getenv("USER")
+		}
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "localhost"
+		}
+		hostInfo.hostname = hostname
+	})
+	return hostInfo.username, hostInfo.hostname
+}
+
+// Домашний каталог в VFS: значение HOME или корень
+func (s *Shell) homeDir() string {
+	if home := s.vars["HOME"]; home != "" {
+		return home
+	}
+	return "/"
+}
+
+// Сокращает домашний каталог в начале пути до ~
+func (s *Shell) abbreviateHome(p string) string {
+	home := path.Clean(s.homeDir())
+	if home == "/" {
+		return p
+	}
+	if p == home {
+		return "~"
+	}
+	if strings.HasPrefix(p, home+"/") {
+		return "~" + p[len(home):]
+	}
+	return p
+}
+
+// Кастомное приглашение к вводу по значению PS1
+func (s *Shell) getInvitation() string {
+	return s.expandPrompt(s.vars["PS1"])
+}
+
+// Раскрывает escape-последовательности приглашения в стиле bash:
+// \u - пользователь, \h и \H - имя хоста (до первой точки и полностью),
+// \w и \W - текущий каталог и его последний элемент (с ~ вместо домашнего каталога),
+// \$ - # для root и $ для остальных, \? - статус последней команды,
+// \t, \T, \A, \@, \d - время и дата, \s - имя оболочки, \n - перевод строки,
+// \e - ESC, \nnn - символ с восьмеричным кодом, \[ и \] - границы непечатаемых символов.
+// Затем в строке раскрываются переменные и подстановки, как внутри двойных кавычек
+func (s *Shell) expandPrompt(ps1 string) string {
+	username, hostname := lookupHostInfo()
+	now := time.Now()
+	var b strings.Builder
+	// Подставленные значения экранируются, чтобы не раскрываться повторно
+	literal := func(str string) {
+		for _, r := range str {
+			if strings.ContainsRune("\\$`\"", r) {
+				b.WriteRune('\\')
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	for i := 0; i < len(ps1); i++ {
+		if ps1[i] == '"' {
+			b.WriteString(`\"`)
+			continue
+		}
+		if ps1[i] != '\\' || i+1 >= len(ps1) {
+			b.WriteByte(ps1[i])
+			continue
+		}
+		i++
+		switch c := ps1[i]; c {
+		case 'u':
+			literal(username)
+		case 'h':
+			literal(strings.SplitN(hostname, ".", 2)[0])
+		case 'H':
+			literal(hostname)
+		case 'w':
+			literal(s.abbreviateHome(s.currentPath))
+		case 'W':
+			dir := s.abbreviateHome(s.currentPath)
+			if dir != "/" && dir != "~" {
+				dir = path.Base(dir)
+			}
+			literal(dir)
+		case '$':
+			if os.Geteuid() == 0 {
+				b.WriteString("#")
+			} else {
+				literal("$")
+			}
+		case '?':
+			b.WriteString(strconv.Itoa(s.status))
+		case 't':
+			b.WriteString(now.Format("15:04:05"))
+		case 'T':
+			b.WriteString(now.Format("03:04:05"))
+		case 'A':
+			b.WriteString(now.Format("15:04"))
+		case '@':
+			b.WriteString(now.Format("03:04 PM"))
+		case 'd':
+			b.WriteString(now.Format("Mon Jan 02"))
+		case 's':
+			b.WriteString(shellName)
+		case 'n':
+			b.WriteString("\n")
+		case 'e':
+			b.WriteString("\x1b")
+		case 'a':
+			b.WriteString("\a")
+		case '[', ']':
+			// непечатаемые последовательности и так не учитываются при расчете ширины
+		case '\\':
+			b.WriteString(`\\`)
+		default:
+			if c >= '0' && c <= '7' && i+2 < len(ps1) {
+				if code, err := strconv.ParseUint(ps1[i:i+3], 8, 8); err == nil {
+					b.WriteByte(byte(code))
+					i += 2
+					continue
+				}
+			}
+			b.WriteString(fmt.Sprintf(`\%c`, c))
+		}
+	}
+	b.WriteByte('"')
+	prompt := s.expandString(b.String())
+	s.expandErr = nil
+	return prompt
+}
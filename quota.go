@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/TimofeyChernyshev/MIREA-Configuration-management-1/vfs"
+)
+
+// Ограничение размера VFS по умолчанию: загрузка большого каталога с диска
+// (например, -vfs . по умолчанию) не должна занимать всю память
+const defaultQuota = "256M"
+
+// Разбирает ограничения размеров из параметров командной строки.
+// userQuotas задается в виде OWNER=SIZE[,OWNER=SIZE...]
+func parseLimits(quota, maxFileSize, userQuotas string) (vfs.Limits, error) {
+	var limits vfs.Limits
+	var err error
+	if limits.MaxBytes, err = parseSize(quota); err != nil {
+		return limits, fmt.Errorf("-quota: %v", err)
+	}
+	if limits.MaxFileSize, err = parseSize(maxFileSize); err != nil {
+		return limits, fmt.Errorf("-max-file-size: %v", err)
+	}
+	for _, item := range strings.Split(userQuotas, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		owner, value, ok := strings.Cut(item, "=")
+		owner = strings.TrimSpace(owner)
+		if !ok || owner == "" {
+			return limits, fmt.Errorf("-user-quota: expected OWNER=SIZE, got '%s'", item)
+		}
+		size, err := parseSize(strings.TrimSpace(value))
+		if err != nil {
+			return limits, fmt.Errorf("-user-quota: %v", err)
+		}
+		if limits.OwnerQuotas == nil {
+			limits.OwnerQuotas = map[string]int64{}
+		}
+		limits.OwnerQuotas[owner] = size
+	}
+	return limits, nil
+}
+
+func (s *Shell) quotaCommand(args []string) {
+	// Выводит место, занятое файлами владельцев, и их квоты
+	opts, owners, ok := s.parseArgs("quota", args)
+	if !ok {
+		return
+	}
+	usage := s.vfs.OwnerUsage()
+	quotas := s.vfs.Limits.OwnerQuotas
+	// Без аргументов - все владельцы, у которых есть файлы или квота
+	if len(owners) == 0 {
+		owners = slices.Sorted(maps.Keys(usage))
+		for owner := range quotas {
+			if _, ok := usage[owner]; !ok {
+				owners = append(owners, owner)
+			}
+		}
+		slices.Sort(owners)
+	}
+	format := func(size int64) string {
+		if opts.Has("h") {
+			return humanSize(size)
+		}
+		return strconv.FormatInt(kilobytes(size), 10)
+	}
+	if !s.recording() {
+		fmt.Fprintf(s.out(), "%-12s %10s %10s %7s\n", "Owner", "Used", "Quota", "Files")
+	}
+	for _, owner := range owners {
+		used := usage[owner]
+		quota, limited := quotas[owner]
+		if s.emit(quotaResult{Owner: owner, Bytes: used.Bytes, Quota: quota, Files: used.Files}) {
+			continue
+		}
+		limit := "-"
+		usedText := format(used.Bytes)
+		if limited {
+			limit = format(quota)
+			// Превышение квоты (например, заданной после загрузки) отмечается *, как в quota(1)
+			if used.Bytes > quota {
+				usedText += "*"
+			}
+		}
+		fmt.Fprintf(s.out(), "%-12s %10s %10s %7d\n", owner, usedText, limit, used.Files)
+	}
+}
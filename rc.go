@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+)
+
+// Файлы конфигурации в VFS, выполняемые перед интерактивным режимом
+const (
+	systemRCFile = "/etc/shellrc"
+	userRCFile   = ".shellrc" // в домашнем каталоге
+)
+
+// Файл приветствия в VFS по умолчанию. Путь задается переменной MOTD, пустое значение отключает вывод
+const defaultMOTDFile = "/motd"
+
+// Выполняет файлы конфигурации в текущем контексте оболочки. Если задан hostPath,
+// выполняется только этот файл с диска, иначе /etc/shellrc и ~/.shellrc из VFS.
+// Отсутствующие файлы VFS пропускаются
+func (s *Shell) loadRC(hostPath string) {
+	if hostPath != "" {
+		content, err := os.ReadFile(hostPath)
+		if err != nil {
+			s.errorf("Error: rc: %v\n", err)
+			return
+		}
+		s.runRC(string(content))
+		return
+	}
+	// HOME, заданный в /etc/shellrc, влияет на поиск ~/.shellrc
+	for _, rcPath := range []string{systemRCFile, ""} {
+		if rcPath == "" {
+			rcPath = path.Join(s.homeDir(), userRCFile)
+		}
+		node, err := s.vfs.FindNode(rcPath)
+		if err != nil || node.IsDir {
+			continue
+		}
+		s.runRC(node.Content)
+	}
+}
+
+func (s *Shell) runRC(content string) {
+	s.callDepth++
+	defer func() { s.callDepth-- }()
+	s.runScript(content, false)
+	// return завершает только файл конфигурации
+	if s.flow != nil && s.flow.kind == flowReturn {
+		s.flow = nil
+	}
+}
+
+// Выводит сообщение дня из файла VFS, заданного переменной MOTD
+func (s *Shell) printMOTD() {
+	motdPath, ok := s.vars["MOTD"]
+	if !ok {
+		motdPath = defaultMOTDFile
+	}
+	if motdPath == "" {
+		return
+	}
+	node, err := s.vfs.FindNode(s.absPath(motdPath))
+	if err == nil && !node.IsDir {
+		fmt.Fprintf(s.out(), "%s\n", node.Content)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/TimofeyChernyshev/MIREA-Configuration-management-1/vfs"
+)
+
+// Интерпретаторы, под которыми скрипт из VFS выполняется этой оболочкой
+var shellInterpreters = map[string]bool{"sh": true, "bash": true, "dash": true, "vfs-shell": true}
+
+// Читает скрипт из VFS или с диска. hostFirst задает, где искать в первую очередь
+func (s *Shell) readScript(scriptPath string, hostFirst bool) (string, error) {
+	readVFS := func() (string, error) {
+		node, err := s.vfs.FindNode(s.absPath(scriptPath))
+		if err != nil {
+			return "", err
+		}
+		if node.IsDir {
+			return "", &vfs.PathError{Op: "open", Path: scriptPath, Err: vfs.ErrIsDir}
+		}
+		return node.Content, nil
+	}
+	readHost := func() (string, error) {
+		content, err := os.ReadFile(scriptPath)
+		return string(content), err
+	}
+	first, second := readVFS, readHost
+	if hostFirst {
+		first, second = readHost, readVFS
+	}
+	content, err := first()
+	if err == nil {
+		return content, nil
+	}
+	if content, err := second(); err == nil {
+		return content, nil
+	}
+	return "", err
+}
+
+// Выполняет текст скрипта по частям, как если бы он вводился построчно.
+// Если echo == true, каждая команда выводится с приглашением к вводу.
+// Выполнение прекращается на return или exit
+func (s *Shell) runScript(content string, echo bool) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	s.runLines(func(prompt string) (string, bool) {
+		if len(lines) == 0 {
+			return "", false
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return line, true
+	}, echo)
+}
+
+// Выполняет команды, считывая строки из next по мере необходимости
+func (s *Shell) runLines(next lineSource, echo bool) {
+	for {
+		input, err := readInput(next, "")
+		if err == io.EOF {
+			break
+		}
+		// пропускаем пустые строки и комментарии
+		if tokens, lexErr := lex(input); lexErr == nil && len(tokens) == 0 {
+			continue
+		}
+		if echo {
+			// выводим команду так, как она была бы введена в интерактивном режиме
+			for i, line := range strings.Split(strings.TrimSpace(input), "\n") {
+				if i == 0 {
+					fmt.Printf("%s%s\n", s.getInvitation(), line)
+				} else {
+					fmt.Printf("%s%s\n", secondaryPrompt, line)
+				}
+			}
+		}
+		if err != nil {
+			s.syntaxError(err)
+			continue
+		}
+		s.runChunk(input)
+		if s.flow != nil {
+			break
+		}
+	}
+}
+
+func (s *Shell) sourceCommand(args []string) {
+	// Выполняет скрипт в текущем контексте оболочки
+	if len(args) == 0 {
+		s.errorf("source: filename argument required\n")
+		s.status = 2
+		return
+	}
+	content, err := s.readScript(args[0], false)
+	if err != nil {
+		s.pathError("source", args[0], err)
+		return
+	}
+	if s.callDepth >= maxCallDepth {
+		s.errorf("source: %s: maximum nesting level exceeded (%d)\n", args[0], maxCallDepth)
+		return
+	}
+	savedParams := s.params
+	if len(args) > 1 {
+		s.params = args[1:]
+	}
+	s.callDepth++
+	defer func() {
+		s.params = savedParams
+		s.callDepth--
+	}()
+	s.runScript(content, false)
+	// return завершает только выполняемый скрипт
+	if s.flow != nil && s.flow.kind == flowReturn {
+		s.flow = nil
+	}
+}
+
+func (s *Shell) shCommand(args []string) {
+	// Выполняет скрипт или строку команд в дочернем контексте
+	opts, operands, ok := s.parseArgs("sh", args)
+	if !ok {
+		return
+	}
+	if opts.Has("c") {
+		if len(operands) == 0 {
+			s.errorf("sh: -c: option requires an argument\n")
+			s.status = 2
+			return
+		}
+		s.runChild("sh", operands[0], operands[1:])
+		return
+	}
+	if len(operands) == 0 {
+		s.errorf("sh: script file argument required\n")
+		s.status = 2
+		return
+	}
+	content, err := s.readScript(operands[0], false)
+	if err != nil {
+		s.pathError("sh", operands[0], err)
+		s.status = 127
+		return
+	}
+	s.runChild(operands[0], content, operands[1:])
+}
+
+// Запускает исполняемый скрипт из VFS с учетом строки #!
+func (s *Shell) runExecutable(scriptPath string, args []string) error {
+	node, err := s.vfs.FindNode(s.absPath(scriptPath))
+	if err != nil {
+		s.status = 127
+		return fmt.Errorf("%s: %w", scriptPath, vfs.Reason(err))
+	}
+	if node.IsDir {
+		s.status = 126
+		return fmt.Errorf("%s: %w", scriptPath, vfs.ErrIsDir)
+	}
+	if strings.HasPrefix(node.Content, "#!") {
+		line, _, _ := strings.Cut(node.Content[2:], "\n")
+		fields := strings.Fields(line)
+		// #!/usr/bin/env sh - интерпретатор указан аргументом env
+		if len(fields) > 1 && path.Base(fields[0]) == "env" {
+			fields = fields[1:]
+		}
+		if len(fields) > 0 && !shellInterpreters[path.Base(fields[0])] {
+			s.status = 126
+			return fmt.Errorf("%s: bad interpreter: %s", scriptPath, fields[0])
+		}
+	}
+	// Файл без строки #! выполняется как скрипт оболочки
+	s.runChild(scriptPath, node.Content, args)
+	return nil
+}
+
+// Выполняет скрипт в дочернем контексте и передает его статус вызывающей оболочке
+func (s *Shell) runChild(name, content string, args []string) {
+	if s.callDepth >= maxCallDepth {
+		s.errorf("%s: maximum nesting level exceeded (%d)\n", name, maxCallDepth)
+		return
+	}
+	child := s.newChild()
+	child.params = args
+	child.vars["0"] = name
+	child.runScript(content, false)
+	s.status = child.status
+}
+
+// Создает дочерний контекст: общая VFS, потоки ввода-вывода, копии текущей директории и переменных.
+// Функции и псевдонимы не наследуются
+func (s *Shell) newChild() *Shell {
+	child := NewShell()
+	child.vfs = s.vfs
+	child.currentPath = s.currentPath
+	maps.Copy(child.vars, s.vars)
+	child.callDepth = s.callDepth + 1
+	child.subshell = true
+	child.stdin = s.stdin
+	child.stdout = s.stdout
+	child.stderr = s.stderr
+	// В режиме JSON структурированные результаты команд дочернего контекста
+	// добавляются к записи вызвавшей его команды
+	child.outputFormat = s.outputFormat
+	child.record = s.record
+	// Дочерний контекст выполняется в той же горутине и разделяет блокировку выполнения
+	child.jobs = s.jobs
+	child.locked = s.locked
+	child.background = s.background
+	child.lastJob = s.lastJob
+	// Команды, зарегистрированные извне через Register, доступны и в дочернем контексте
+	for _, name := range s.commandOrder {
+		if _, exists := child.commands[name]; !exists {
+			child.Register(s.commands[name])
+		}
+	}
+	return child
+}
+
+// Подоболочка для подстановки $(...): кроме переменных наследует функции, алиасы
+// и позиционные параметры
+func (s *Shell) newSubshell() *Shell {
+	child := s.newChild()
+	maps.Copy(child.functions, s.functions)
+	maps.Copy(child.aliases, s.aliases)
+	child.params = slices.Clone(s.params)
+	return child
+}
@@ -0,0 +1,491 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Адрес команды sed: номер строки, последняя строка ($) или регулярное выражение
+type sedAddress struct {
+	line int
+	last bool
+	re   *regexp.Regexp
+}
+
+// Подходит ли строка номер n под адрес
+func (a *sedAddress) matches(n int, last bool, line string) bool {
+	switch {
+	case a.re != nil:
+		return a.re.MatchString(line)
+	case a.last:
+		return last
+	}
+	return n == a.line
+}
+
+// Команда sed с адресами: s, d, p, q или =
+type sedCommand struct {
+	from, to *sedAddress
+	negate   bool // ! после адреса
+	active   bool // внутри диапазона from,to
+	name     byte
+
+	// s/RE/REPLACEMENT/FLAGS
+	re          *regexp.Regexp
+	replacement string
+	global      bool // g - заменить все вхождения
+	nth         int  // N - заменить только N-е вхождение
+	print       bool // p - вывести строку после замены
+}
+
+// Выбирает ли команда строку номер n. Диапазон from,to начинается со строки,
+// подходящей под from, и заканчивается строкой, подходящей под to
+func (c *sedCommand) selects(n int, last bool, line string) bool {
+	matched := false
+	switch {
+	case c.from == nil:
+		matched = true
+	case c.to == nil:
+		matched = c.from.matches(n, last, line)
+	case c.active:
+		matched = true
+		if c.to.re != nil && c.to.re.MatchString(line) || c.to.re == nil && (c.to.last && last || !c.to.last && n >= c.to.line) {
+			c.active = false
+		}
+	case c.from.matches(n, last, line):
+		matched = true
+		// Номер строки конца, не больший начала, дает диапазон из одной строки
+		c.active = c.to.re != nil || c.to.last && !last || !c.to.last && c.to.line > n
+	}
+	return matched != c.negate
+}
+
+// Разбор скрипта sed: команды разделяются ";" или переводом строки
+type sedParser struct {
+	script string
+	pos    int
+	ere    bool // -E: расширенные регулярные выражения
+}
+
+func (p *sedParser) peek() byte {
+	if p.pos >= len(p.script) {
+		return 0
+	}
+	return p.script[p.pos]
+}
+
+func (p *sedParser) skipSpaces() {
+	for p.pos < len(p.script) && (p.script[p.pos] == ' ' || p.script[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// Читает текст до неэкранированного разделителя delim; "\delim" заменяется на delim
+func (p *sedParser) delimited(delim byte) (string, error) {
+	var b strings.Builder
+	for p.pos < len(p.script) {
+		c := p.script[p.pos]
+		if c == delim {
+			p.pos++
+			return b.String(), nil
+		}
+		if c == '\\' && p.pos+1 < len(p.script) {
+			next := p.script[p.pos+1]
+			if next == delim {
+				b.WriteByte(delim)
+			} else if next == 'n' && delim != 'n' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(c)
+				b.WriteByte(next)
+			}
+			p.pos += 2
+			continue
+		}
+		b.WriteByte(c)
+		p.pos++
+	}
+	return "", fmt.Errorf("unterminated address regex")
+}
+
+func (p *sedParser) compile(pattern string, ignoreCase bool) (*regexp.Regexp, error) {
+	if !p.ere {
+		pattern = breToRegexp(pattern)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %v", err)
+	}
+	return re, nil
+}
+
+func (p *sedParser) parseAddress() (*sedAddress, error) {
+	switch c := p.peek(); {
+	case c >= '0' && c <= '9':
+		start := p.pos
+		for p.pos < len(p.script) && p.script[p.pos] >= '0' && p.script[p.pos] <= '9' {
+			p.pos++
+		}
+		n, _ := strconv.Atoi(p.script[start:p.pos])
+		if n == 0 {
+			return nil, fmt.Errorf("invalid usage of line address 0")
+		}
+		return &sedAddress{line: n}, nil
+	case c == '$':
+		p.pos++
+		return &sedAddress{last: true}, nil
+	case c == '/':
+		p.pos++
+		pattern, err := p.delimited('/')
+		if err != nil {
+			return nil, err
+		}
+		re, err := p.compile(pattern, false)
+		if err != nil {
+			return nil, err
+		}
+		return &sedAddress{re: re}, nil
+	}
+	return nil, nil
+}
+
+func (p *sedParser) parse() ([]*sedCommand, error) {
+	var commands []*sedCommand
+	for {
+		for p.pos < len(p.script) && strings.IndexByte(" \t\n;", p.script[p.pos]) >= 0 {
+			p.pos++
+		}
+		if p.pos >= len(p.script) {
+			return commands, nil
+		}
+		cmd := &sedCommand{}
+		var err error
+		if cmd.from, err = p.parseAddress(); err != nil {
+			return nil, err
+		}
+		if cmd.from != nil && p.peek() == ',' {
+			p.pos++
+			if cmd.to, err = p.parseAddress(); err != nil {
+				return nil, err
+			}
+			if cmd.to == nil {
+				return nil, fmt.Errorf("unexpected ','")
+			}
+		}
+		p.skipSpaces()
+		if p.peek() == '!' {
+			cmd.negate = true
+			p.pos++
+			p.skipSpaces()
+		}
+		cmd.name = p.peek()
+		p.pos++
+		switch cmd.name {
+		case 'd', 'p', 'q', '=':
+		case 's':
+			if err := p.parseSubstitute(cmd); err != nil {
+				return nil, err
+			}
+		case 0:
+			return nil, fmt.Errorf("missing command")
+		default:
+			return nil, fmt.Errorf("unknown command: '%c'", cmd.name)
+		}
+		p.skipSpaces()
+		if c := p.peek(); c != 0 && c != ';' && c != '\n' {
+			return nil, fmt.Errorf("extra characters after command")
+		}
+		commands = append(commands, cmd)
+	}
+}
+
+// s/RE/REPLACEMENT/FLAGS, разделителем может быть любой символ после s
+func (p *sedParser) parseSubstitute(cmd *sedCommand) error {
+	delim := p.peek()
+	if delim == 0 || delim == '\n' || delim == '\\' {
+		return fmt.Errorf("unterminated `s' command")
+	}
+	p.pos++
+	pattern, err := p.delimited(delim)
+	if err != nil {
+		return fmt.Errorf("unterminated `s' command")
+	}
+	if cmd.replacement, err = p.delimited(delim); err != nil {
+		return fmt.Errorf("unterminated `s' command")
+	}
+	ignoreCase := false
+	for p.pos < len(p.script) {
+		switch c := p.script[p.pos]; {
+		case c == 'g':
+			cmd.global = true
+		case c == 'p':
+			cmd.print = true
+		case c == 'i' || c == 'I':
+			ignoreCase = true
+		case c >= '1' && c <= '9':
+			start := p.pos
+			for p.pos+1 < len(p.script) && p.script[p.pos+1] >= '0' && p.script[p.pos+1] <= '9' {
+				p.pos++
+			}
+			cmd.nth, _ = strconv.Atoi(p.script[start : p.pos+1])
+		case c == ' ' || c == '\t' || c == ';' || c == '\n':
+			cmd.re, err = p.compile(pattern, ignoreCase)
+			return err
+		default:
+			return fmt.Errorf("unknown option to `s'")
+		}
+		p.pos++
+	}
+	cmd.re, err = p.compile(pattern, ignoreCase)
+	return err
+}
+
+// Переводит базовое регулярное выражение POSIX (BRE) в синтаксис regexp:
+// \( \) \{ \} \+ \? \| становятся операторами, а те же символы без "\" - обычными
+func breToRegexp(pattern string) string {
+	var b strings.Builder
+	inBracket := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case inBracket:
+			if c == ']' {
+				inBracket = false
+			}
+			b.WriteByte(c)
+		case c == '[':
+			inBracket = true
+			b.WriteByte(c)
+			// "]" сразу после "[" или "[^" входит в набор
+			if i+1 < len(pattern) && pattern[i+1] == '^' {
+				b.WriteByte('^')
+				i++
+			}
+			if i+1 < len(pattern) && pattern[i+1] == ']' {
+				b.WriteString(`\]`)
+				i++
+			}
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			if strings.IndexByte("(){}+?|", pattern[i]) >= 0 {
+				b.WriteByte(pattern[i])
+			} else {
+				b.WriteByte('\\')
+				b.WriteByte(pattern[i])
+			}
+		case strings.IndexByte("(){}+?|", c) >= 0:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Выполняет замену s///: & - найденный текст, \1..\9 - группы, \n - перевод строки
+func (c *sedCommand) substitute(line string) (string, bool) {
+	matches := c.re.FindAllStringSubmatchIndex(line, -1)
+	var b strings.Builder
+	last, replaced := 0, false
+	for i, m := range matches {
+		// Без флагов заменяется первое вхождение, с N - N-е, с g - все (с Ng - начиная с N-го)
+		n := max(c.nth, 1)
+		if i+1 < n || i+1 > n && !c.global {
+			continue
+		}
+		b.WriteString(line[last:m[0]])
+		for j := 0; j < len(c.replacement); j++ {
+			ch := c.replacement[j]
+			switch {
+			case ch == '&':
+				b.WriteString(line[m[0]:m[1]])
+			case ch == '\\' && j+1 < len(c.replacement):
+				j++
+				next := c.replacement[j]
+				switch {
+				case next >= '0' && next <= '9':
+					if g := int(next - '0'); 2*g+1 < len(m) && m[2*g] >= 0 {
+						b.WriteString(line[m[2*g]:m[2*g+1]])
+					}
+				case next == 'n':
+					b.WriteByte('\n')
+				case next == 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(next)
+				}
+			default:
+				b.WriteByte(ch)
+			}
+		}
+		last = m[1]
+		replaced = true
+	}
+	if !replaced {
+		return line, false
+	}
+	b.WriteString(line[last:])
+	return b.String(), true
+}
+
+// Вывод sed. Строка, у которой во входе не было завершающего перевода строки,
+// выводится без него; если за ней выводится что-то еще, перевод строки добавляется
+type sedOutput struct {
+	w       io.Writer
+	missing bool // последняя выведенная строка не завершена переводом строки
+}
+
+func (o *sedOutput) write(text string, newline bool) {
+	if o.missing {
+		io.WriteString(o.w, "\n")
+	}
+	io.WriteString(o.w, text)
+	if newline {
+		io.WriteString(o.w, "\n")
+	}
+	o.missing = !newline
+}
+
+// Выполняет скрипт над строкой номер n. newline - завершалась ли строка во входе
+// переводом строки. Возвращает true, если выполнена команда q
+func runSed(commands []*sedCommand, line string, newline bool, n int, last, quiet bool, out *sedOutput) bool {
+	deleted, quit := false, false
+commands:
+	for _, cmd := range commands {
+		if !cmd.selects(n, last, line) {
+			continue
+		}
+		switch cmd.name {
+		case 'd':
+			deleted = true
+			break commands
+		case 'p':
+			out.write(line, newline)
+		case '=':
+			out.write(strconv.Itoa(n), true)
+		case 'q':
+			quit = true
+			break commands
+		case 's':
+			if result, ok := cmd.substitute(line); ok {
+				line = result
+				if cmd.print {
+					out.write(line, newline)
+				}
+			}
+		}
+	}
+	if !deleted && !quiet {
+		out.write(line, newline)
+	}
+	return quit
+}
+
+func (s *Shell) sedCommand(args []string) {
+	// Потоковый редактор: применяет скрипт к каждой строке файлов или стандартного ввода
+	opts, operands, ok := s.parseArgs("sed", args)
+	if !ok {
+		return
+	}
+	scripts := opts.Values("e")
+	if len(scripts) == 0 {
+		if len(operands) == 0 {
+			s.errorf("sed: no script specified\n")
+			s.status = 2
+			return
+		}
+		scripts, operands = operands[:1], operands[1:]
+	}
+	parser := &sedParser{script: strings.Join(scripts, "\n"), ere: opts.Has("E")}
+	commands, err := parser.parse()
+	if err != nil {
+		s.errorf("sed: -e expression #1, char %d: %v\n", parser.pos, err)
+		s.status = 2
+		return
+	}
+	inPlace := opts.Has("i")
+	files := operands
+	if len(files) == 0 {
+		if inPlace {
+			s.errorf("sed: no input files\n")
+			s.status = 2
+			return
+		}
+		files = []string{"-"}
+	}
+	// Строки выводятся по мере обработки; новое содержимое файла при -i
+	// и результат в режиме JSON накапливаются
+	var buf strings.Builder
+	out := &sedOutput{w: s.out()}
+	if inPlace || s.recording() {
+		out.w = &buf
+	}
+	lineNum := 0
+	for i, file := range files {
+		if inPlace && file == "-" {
+			s.errorf("sed: couldn't edit -: not a regular file\n")
+			s.status = 4 // как в GNU sed
+			return
+		}
+		input, ok := s.openInput("sed", file)
+		if !ok {
+			continue
+		}
+		// С -i каждый файл обрабатывается отдельно: нумерация строк и $ - в пределах файла
+		if inPlace {
+			lineNum = 0
+			out.missing = false
+			for _, cmd := range commands {
+				cmd.active = false
+			}
+		}
+		quit := false
+		for !quit && input.scan() {
+			lineNum++
+			last := input.last() && (inPlace || i == len(files)-1)
+			quit = runSed(commands, input.line, input.newline, lineNum, last, opts.Has("n"), out)
+		}
+		input.close()
+		if input.err != nil {
+			// Файл, прочитанный не до конца, не перезаписывается
+			s.errorf("sed: %v\n", input.err)
+			buf.Reset()
+			continue
+		}
+		if inPlace {
+			s.writeInPlace("sed", file, buf.String())
+			buf.Reset()
+		}
+		if quit {
+			break
+		}
+	}
+	if !inPlace {
+		s.emit(linesResult{Lines: splitLines(buf.String())})
+	}
+}
+
+// Заменяет содержимое файла VFS с учетом ограничений: при их нарушении файл не меняется
+func (s *Shell) writeInPlace(cmd, name, content string) {
+	f, err := s.vfs.OpenFile(s.absPath(name), os.O_WRONLY)
+	if err != nil {
+		s.pathError(cmd, name, err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.WriteAt([]byte(content), 0); err != nil {
+		s.pathError(cmd, name, err)
+		return
+	}
+	if err := f.Truncate(int64(len(content))); err != nil {
+		s.pathError(cmd, name, err)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TimofeyChernyshev/MIREA-Configuration-management-1/vfs"
+)
+
+// Формат времени в выводе stat, как в GNU stat
+const statTimeFormat = "2006-01-02 15:04:05.000000000 -0700"
+
+// Форматы даты, принимаемые touch -d
+var touchDateFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func (s *Shell) statCommand(args []string) {
+	// Выводит метаданные файлов и каталогов
+	opts, files, ok := s.parseArgs("stat", args)
+	if !ok {
+		return
+	}
+	if len(files) == 0 {
+		s.errorf("stat: missing operand\n")
+		return
+	}
+	for _, file := range files {
+		st, err := s.vfs.StatPath(s.absPath(file))
+		if err != nil {
+			s.pathError("stat", file, err)
+			continue
+		}
+		st.Path = file
+		if s.emit(newStatResult(st)) {
+			continue
+		}
+		if opts.Has("c") {
+			fmt.Fprintln(s.out(), formatStat(opts.Value("c"), st))
+			continue
+		}
+		fmt.Fprint(s.out(), formatStat(defaultStatFormat, st))
+	}
+}
+
+// Вывод stat по умолчанию
+const defaultStatFormat = `  File: %n
+  Size: %-10s	Inode: %-10i	Links: %h	%F
+Access: (%04a/%A)  Uid: (%U)  Gid: (%G)
+Access: %x
+Modify: %y
+Change: %z
+ Birth: %w
+`
+
+// Раскрывает в формате stat -c последовательности: %n - имя, %s - размер, %F - тип,
+// %a и %A - права в восьмеричном и символьном виде, %U и %G - владелец и группа,
+// %i - номер узла, %h - число ссылок, %x, %y, %z, %w - время доступа, изменения,
+// изменения метаданных и создания, %X, %Y, %Z, %W - то же в секундах от начала эпохи.
+// Между % и буквой может стоять ширина поля, например %-10s
+func formatStat(format string, st *vfs.StatInfo) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		// Ширина поля: необязательный минус и цифры
+		j := i + 1
+		for j < len(format) && (format[j] == '-' || format[j] >= '0' && format[j] <= '9') {
+			j++
+		}
+		if j >= len(format) {
+			b.WriteString(format[i:])
+			break
+		}
+		value, ok := statField(format[j], st)
+		if !ok {
+			b.WriteString(format[i : j+1])
+		} else {
+			fmt.Fprintf(&b, "%"+format[i+1:j]+"s", value)
+		}
+		i = j
+	}
+	return b.String()
+}
+
+func statField(verb byte, st *vfs.StatInfo) (string, bool) {
+	orUnknown := func(name string) string {
+		if name == "" {
+			return "-"
+		}
+		return name
+	}
+	humanTime := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format(statTimeFormat)
+	}
+	epoch := func(t time.Time) string {
+		if t.IsZero() {
+			return "0"
+		}
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	switch verb {
+	case '%':
+		return "%", true
+	case 'n':
+		return st.Path, true
+	case 's':
+		return strconv.FormatInt(st.Size, 10), true
+	case 'F':
+		return st.Type(), true
+	case 'a':
+		return strconv.FormatUint(uint64(st.Mode.Perm()), 8), true
+	case 'A':
+		return st.Mode.String(), true
+	case 'U':
+		return orUnknown(st.Owner), true
+	case 'G':
+		return orUnknown(st.Group), true
+	case 'i':
+		return strconv.FormatUint(st.Inode, 10), true
+	case 'h':
+		return strconv.Itoa(st.Links), true
+	case 'x':
+		return humanTime(st.ATime), true
+	case 'y':
+		return humanTime(st.MTime), true
+	case 'z':
+		return humanTime(st.CTime), true
+	case 'w':
+		return humanTime(st.BirthTime), true
+	case 'X':
+		return epoch(st.ATime), true
+	case 'Y':
+		return epoch(st.MTime), true
+	case 'Z':
+		return epoch(st.CTime), true
+	case 'W':
+		return epoch(st.BirthTime), true
+	}
+	return "", false
+}
+
+func (s *Shell) touchCommand(args []string) {
+	// Обновляет время доступа и изменения файлов, создавая отсутствующие
+	opts, files, ok := s.parseArgs("touch", args)
+	if !ok {
+		return
+	}
+	if len(files) == 0 {
+		s.errorf("touch: missing file operand\n")
+		return
+	}
+	if opts.Has("d") && opts.Has("r") {
+		s.errorf("touch: cannot specify times from more than one source\n")
+		return
+	}
+	now := time.Now()
+	atime, mtime := now, now
+	switch {
+	case opts.Has("d"):
+		t, err := parseTouchDate(opts.Value("d"))
+		if err != nil {
+			s.errorf("touch: invalid date format '%s'\n", opts.Value("d"))
+			return
+		}
+		atime, mtime = t, t
+	case opts.Has("r"):
+		ref, err := s.vfs.StatPath(s.absPath(opts.Value("r")))
+		if err != nil {
+			s.pathError("touch", opts.Value("r"), err)
+			return
+		}
+		atime, mtime = ref.ATime, ref.MTime
+	}
+	// Без -a и -m меняются оба времени
+	setAccess := opts.Has("a") || !opts.Has("m")
+	setModify := opts.Has("m") || !opts.Has("a")
+
+	for _, file := range files {
+		filePath := s.absPath(file)
+		node, err := s.vfs.FindNode(filePath)
+		if errors.Is(err, vfs.ErrNotExist) {
+			if opts.Has("c") {
+				continue
+			}
+			var f *vfs.File
+			if f, err = s.vfs.OpenFile(filePath, os.O_WRONLY|os.O_CREATE); err == nil {
+				f.Close()
+				node, err = s.vfs.FindNode(filePath)
+			}
+		}
+		if err != nil {
+			s.pathError("touch", file, err)
+			continue
+		}
+		if setAccess {
+			node.ATime = atime
+		}
+		if setModify {
+			node.ModTime = mtime
+		}
+		node.CTime = now
+	}
+}
+
+// Разбирает дату touch -d: @секунды от начала эпохи или дата в одном из форматов touchDateFormats
+func parseTouchDate(value string) (time.Time, error) {
+	if seconds, ok := strings.CutPrefix(value, "@"); ok {
+		n, err := strconv.ParseInt(seconds, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(n, 0), nil
+	}
+	for _, layout := range touchDateFormats {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// Результат stat в режиме JSON
+func newStatResult(st *vfs.StatInfo) statResult {
+	return statResult{
+		Path:      st.Path,
+		Type:      nodeType(st.IsDir),
+		Size:      st.Size,
+		Mode:      fmt.Sprintf("%04o", st.Mode.Perm()),
+		Owner:     st.Owner,
+		Group:     st.Group,
+		Inode:     st.Inode,
+		Links:     st.Links,
+		ATime:     st.ATime,
+		MTime:     st.MTime,
+		CTime:     st.CTime,
+		BirthTime: st.BirthTime,
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TimofeyChernyshev/MIREA-Configuration-management-1/vfs"
+)
+
+// Интервал опроса файлов в режиме tail -f
+var followInterval = 100 * time.Millisecond
+
+// Разбирает число строк или байт для tail: N - последние N, +N - начиная с N-го
+func parseTailCount(value string) (n int, fromStart bool, err error) {
+	number := strings.TrimPrefix(value, "-")
+	if rest, ok := strings.CutPrefix(value, "+"); ok {
+		number, fromStart = rest, true
+	}
+	n, err = strconv.Atoi(number)
+	if err != nil || n < 0 || strings.HasPrefix(number, "+") || strings.HasPrefix(number, "-") {
+		return 0, false, errors.New("invalid number")
+	}
+	return n, fromStart, nil
+}
+
+// Позиция, с которой tail выводит содержимое. Завершающий перевод строки
+// не образует отдельную строку: последняя строка "a\nb\n" - "b\n"
+func tailStart(content string, n int, fromStart, bytes bool) int {
+	switch {
+	case bytes && fromStart:
+		return min(max(n-1, 0), len(content))
+	case bytes:
+		return max(len(content)-n, 0)
+	case fromStart:
+		pos := 0
+		for i := 1; i < n; i++ {
+			j := strings.IndexByte(content[pos:], '\n')
+			if j < 0 {
+				return len(content)
+			}
+			pos += j + 1
+		}
+		return pos
+	}
+	if n == 0 {
+		return len(content)
+	}
+	pos := len(strings.TrimSuffix(content, "\n"))
+	for range n {
+		j := strings.LastIndexByte(content[:pos], '\n')
+		if j < 0 {
+			return 0
+		}
+		pos = j
+	}
+	return pos + 1
+}
+
+// Файл, за которым следит tail -f: новые данные читаются с позиции offset
+type followedFile struct {
+	arg    string // имя файла, как оно указано в команде
+	name   string // имя в заголовке
+	file   *vfs.File
+	offset int64
+}
+
+// Открывает файл VFS и читает его содержимое через дескриптор, под блокировкой VFS,
+// так как файл могут одновременно дописывать другие владельцы VFS
+func (s *Shell) readFollowed(fileArg string) (*vfs.File, string, bool) {
+	f, err := s.vfs.OpenFile(s.absPath(fileArg), os.O_RDONLY)
+	if err != nil {
+		s.pathError("tail", fileArg, err)
+		return nil, "", false
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		f.Close()
+		s.pathError("tail", fileArg, err)
+		return nil, "", false
+	}
+	return f, string(data), true
+}
+
+func (s *Shell) tailCommand(args []string) {
+	// Выводит последние строки или байты файлов (по умолчанию 10 строк)
+	opts, files, ok := s.parseArgs("tail", args)
+	if !ok {
+		return
+	}
+	count, fromStart, bytes := 10, false, opts.Has("c")
+	if opts.Has("n") || bytes {
+		flag, what := "n", "lines"
+		if bytes {
+			flag, what = "c", "bytes"
+		}
+		n, from, err := parseTailCount(opts.Value(flag))
+		if err != nil {
+			s.errorf("tail: invalid number of %s: '%s'\n", what, opts.Value(flag))
+			s.status = 2
+			return
+		}
+		count, fromStart = n, from
+	}
+	if opts.Has("f") && s.recording() {
+		// Запись JSON выводится после завершения команды, а tail -f не завершается сам
+		s.errorf("tail: option -f is not supported with JSON output\n")
+		s.status = 2
+		return
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	headers := len(files) > 1 && !opts.Has("q") || opts.Has("v")
+	printed := false
+	// Заголовок "==> FILE <==", перед всеми, кроме первого, - пустая строка
+	header := func(name string) {
+		if printed {
+			fmt.Fprintln(s.out())
+		}
+		fmt.Fprintf(s.out(), "==> %s <==\n", name)
+		printed = true
+	}
+	var followed []*followedFile
+	for _, fileArg := range files {
+		name, file := fileArg, ""
+		var content string
+		var handle *vfs.File
+		if fileArg == "-" {
+			name = "standard input"
+			if content, ok = s.readInput("tail", fileArg); !ok {
+				continue
+			}
+		} else {
+			file = s.absPath(fileArg)
+			if handle, content, ok = s.readFollowed(fileArg); !ok {
+				continue
+			}
+			defer handle.Close()
+		}
+		output := content[tailStart(content, count, fromStart, bytes):]
+		if s.emit(linesResult{File: file, Lines: splitLines(output)}) {
+			continue
+		}
+		if headers {
+			header(name)
+		}
+		io.WriteString(s.out(), output)
+		// Стандартный ввод уже прочитан до конца, следить можно только за файлами VFS.
+		// Слежение продолжается с позиции, до которой файл прочитан через тот же дескриптор
+		if opts.Has("f") && handle != nil {
+			followed = append(followed, &followedFile{arg: fileArg, name: name, file: handle, offset: int64(len(content))})
+		}
+	}
+	if len(followed) > 0 {
+		s.follow(followed, headers)
+	}
+}
+
+// Выводит данные, дописанные в файлы, пока не придет Ctrl-C (SIGINT).
+// Сигнал перехватывается, поэтому прерывается только tail, а не оболочка.
+// Между проверками блокировка выполнения отпускается, и фоновые задания могут дописывать файлы
+func (s *Shell) follow(files []*followedFile, headers bool) {
+	interrupt, stop := s.interrupts()
+	defer stop()
+	last := files[len(files)-1]
+	buf := make([]byte, 32*1024)
+	for {
+		if !s.await(after(followInterval), interrupt) {
+			s.status = 130 // 128 + SIGINT, как у процесса, прерванного Ctrl-C
+			return
+		}
+		for _, f := range files {
+			// Файл стал короче прочитанной части - выводится с начала
+			if size, err := f.file.Seek(0, io.SeekEnd); err == nil && size < f.offset {
+				s.errorf("tail: %s: file truncated\n", f.arg)
+				f.offset = 0
+			}
+			for {
+				n, err := f.file.ReadAt(buf, f.offset)
+				if n > 0 {
+					// Заголовок выводится при переходе к данным другого файла
+					if headers && f != last {
+						fmt.Fprintf(s.out(), "\n==> %s <==\n", f.name)
+						last = f
+					}
+					s.out().Write(buf[:n])
+					f.offset += int64(n)
+				}
+				if err != nil {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build darwin || freebsd || netbsd || openbsd
+
+package main
+
+import "syscall"
+
+// Запросы ioctl для чтения и установки режима терминала
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
@@ -0,0 +1,11 @@
+//go:build linux
+
+package main
+
+import "syscall"
+
+// Запросы ioctl для чтения и установки режима терминала
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
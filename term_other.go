@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !windows
+
+package main
+
+import "errors"
+
+// На прочих платформах редактор строки недоступен и ввод читается построчно
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+func makeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported")
+}
@@ -0,0 +1,50 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+// Режимы консоли Windows
+const (
+	enableProcessedInput            = 0x0001
+	enableLineInput                 = 0x0002
+	enableEchoInput                 = 0x0004
+	enableVirtualTerminalInput      = 0x0200
+	enableVirtualTerminalProcessing = 0x0004
+)
+
+var setConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// Является ли дескриптор консолью
+func isTerminal(fd uintptr) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(fd), &mode) == nil
+}
+
+// Переводит консоль в посимвольный режим без эха. Клавиши передаются
+// escape-последовательностями VT100, как в терминалах UNIX.
+// Возвращает функцию, восстанавливающую прежний режим
+func makeRaw(fd uintptr) (func(), error) {
+	in := syscall.Handle(fd)
+	var inMode uint32
+	if err := syscall.GetConsoleMode(in, &inMode); err != nil {
+		return nil, err
+	}
+	raw := inMode&^(enableProcessedInput|enableLineInput|enableEchoInput) | enableVirtualTerminalInput
+	if r, _, err := setConsoleMode.Call(uintptr(in), uintptr(raw)); r == 0 {
+		return nil, err
+	}
+	// Вывод также должен понимать escape-последовательности перемещения курсора
+	out, _ := syscall.GetStdHandle(syscall.STD_OUTPUT_HANDLE)
+	var outMode uint32
+	outOK := syscall.GetConsoleMode(out, &outMode) == nil
+	if outOK {
+		setConsoleMode.Call(uintptr(out), uintptr(outMode|enableVirtualTerminalProcessing))
+	}
+	return func() {
+		setConsoleMode.Call(uintptr(in), uintptr(inMode))
+		if outOK {
+			setConsoleMode.Call(uintptr(out), uintptr(outMode))
+		}
+	}, nil
+}
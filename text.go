@@ -0,0 +1,721 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/TimofeyChernyshev/MIREA-Configuration-management-1/vfs"
+)
+
+// Читает содержимое файла VFS или стандартный ввод, если имя не задано или равно "-".
+// При ошибке выводит сообщение и возвращает ok == false
+func (s *Shell) readInput(cmd, name string) (string, bool) {
+	if name == "" || name == "-" {
+		data, err := io.ReadAll(s.in())
+		if err != nil {
+			s.errorf("%s: %v\n", cmd, err)
+			return "", false
+		}
+		return string(data), true
+	}
+	node, err := s.vfs.FindNode(s.absPath(name))
+	if err != nil {
+		s.pathError(cmd, name, err)
+		return "", false
+	}
+	if node.IsDir {
+		s.pathError(cmd, name, vfs.ErrIsDir)
+		return "", false
+	}
+	return node.Content, true
+}
+
+// Построчное чтение входа команды без загрузки его в память целиком
+type lineReader struct {
+	r       *bufio.Reader
+	file    *vfs.File // nil для стандартного ввода
+	line    string    // текущая строка без перевода строки
+	newline bool      // завершалась ли текущая строка переводом строки
+	err     error     // ошибка чтения, кроме io.EOF
+}
+
+// Открывает для построчного чтения файл VFS или стандартный ввод, если имя не задано
+// или равно "-". При ошибке выводит сообщение и возвращает ok == false
+func (s *Shell) openInput(cmd, name string) (*lineReader, bool) {
+	if name == "" || name == "-" {
+		return &lineReader{r: bufio.NewReader(s.in())}, true
+	}
+	f, err := s.vfs.OpenFile(s.absPath(name), os.O_RDONLY)
+	if err != nil {
+		s.pathError(cmd, name, err)
+		return nil, false
+	}
+	if info, _ := f.Stat(); info.IsDir() {
+		f.Close()
+		s.pathError(cmd, name, vfs.ErrIsDir)
+		return nil, false
+	}
+	return &lineReader{r: bufio.NewReader(f), file: f}, true
+}
+
+// Читает следующую строку. Возвращает false в конце ввода или при ошибке
+func (l *lineReader) scan() bool {
+	line, err := l.r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		l.err = err
+	}
+	if line == "" {
+		return false
+	}
+	l.line, l.newline = strings.CutSuffix(line, "\n")
+	return true
+}
+
+// Была ли прочитанная строка последней
+func (l *lineReader) last() bool {
+	_, err := l.r.Peek(1)
+	return err != nil
+}
+
+func (l *lineReader) close() {
+	if l.file != nil {
+		l.file.Close()
+	}
+}
+
+// Разбивает текст на строки. Завершающий перевод строки не образует пустую строку
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// Выводит строки результата: в файл VFS, если он задан, иначе в стандартный вывод
+func (s *Shell) writeLines(cmd, file, output string, lines []string) {
+	if output == "" {
+		if s.emit(linesResult{File: file, Lines: lines}) {
+			return
+		}
+		for _, line := range lines {
+			fmt.Fprintln(s.out(), line)
+		}
+		return
+	}
+	f, err := s.vfs.Create(s.absPath(output))
+	if err != nil {
+		s.pathError(cmd, output, err)
+		return
+	}
+	defer f.Close()
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		s.pathError(cmd, output, err)
+	}
+}
+
+// Поток вывода команды, обрабатывающей ввод построчно: стандартный вывод или, в режиме
+// JSON, буфер, строки которого добавляются к результату команды вызовом flush
+func (s *Shell) streamOut() (w io.Writer, flush func()) {
+	if !s.recording() {
+		return s.out(), func() {}
+	}
+	var buf strings.Builder
+	return &buf, func() { s.emit(linesResult{Lines: splitLines(buf.String())}) }
+}
+
+func (s *Shell) uniqCommand(args []string) {
+	// Выводит строки без повторов, стоящих рядом
+	opts, operands, ok := s.parseArgs("uniq", args)
+	if !ok {
+		return
+	}
+	if len(operands) > 2 {
+		s.errorf("uniq: extra operand '%s'\n", operands[2])
+		s.status = 2
+		return
+	}
+	skipFields, skipChars := 0, 0
+	for _, flag := range []struct {
+		name  string
+		value *int
+	}{{"f", &skipFields}, {"s", &skipChars}} {
+		if !opts.Has(flag.name) {
+			continue
+		}
+		n, err := strconv.Atoi(opts.Value(flag.name))
+		if err != nil || n < 0 {
+			s.errorf("uniq: %s: invalid number\n", opts.Value(flag.name))
+			s.status = 2
+			return
+		}
+		*flag.value = n
+	}
+	input, output := "", ""
+	if len(operands) > 0 {
+		input = operands[0]
+	}
+	if len(operands) > 1 {
+		output = operands[1]
+	}
+	content, ok := s.readInput("uniq", input)
+	if !ok {
+		return
+	}
+	// Ключ сравнения: строка без первых skipFields полей и затем первых skipChars символов
+	key := func(line string) string {
+		rest := line
+		for range skipFields {
+			rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+			if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+				rest = rest[i:]
+			} else {
+				rest = ""
+			}
+		}
+		runes := []rune(rest)
+		rest = string(runes[min(skipChars, len(runes)):])
+		if opts.Has("i") {
+			rest = strings.ToLower(rest)
+		}
+		return rest
+	}
+	var result []string
+	lines := splitLines(content)
+	for i := 0; i < len(lines); {
+		// Группа одинаковых строк, стоящих рядом; выводится первая из них
+		j := i + 1
+		for j < len(lines) && key(lines[j]) == key(lines[i]) {
+			j++
+		}
+		count := j - i
+		if !(opts.Has("d") && count == 1 || opts.Has("u") && count > 1) {
+			line := lines[i]
+			if opts.Has("c") {
+				line = fmt.Sprintf("%7d %s", count, line)
+			}
+			result = append(result, line)
+		}
+		i = j
+	}
+	file := ""
+	if input != "" && input != "-" {
+		file = s.absPath(input)
+	}
+	s.writeLines("uniq", file, output, result)
+}
+
+// Ключ сортировки -k START[,END][nr]: номера полей с 1, END = 0 - до конца строки
+type sortKey struct {
+	start, end int
+	numeric    bool
+	reverse    bool
+}
+
+// Разбирает определение ключа -k
+func parseSortKey(def string) (sortKey, error) {
+	var key sortKey
+	spec := strings.TrimRightFunc(def, func(r rune) bool {
+		switch r {
+		case 'n':
+			key.numeric = true
+		case 'r':
+			key.reverse = true
+		default:
+			return false
+		}
+		return true
+	})
+	startText, endText, hasEnd := strings.Cut(spec, ",")
+	start, err := strconv.Atoi(startText)
+	if err != nil || start < 1 {
+		return key, fmt.Errorf("invalid field specification '%s'", def)
+	}
+	key.start = start
+	if hasEnd {
+		end, err := strconv.Atoi(endText)
+		if err != nil || end < start {
+			return key, fmt.Errorf("invalid field specification '%s'", def)
+		}
+		key.end = end
+	}
+	return key, nil
+}
+
+// Числовое значение начала строки для sort -n: пробелы, знак, цифры и дробная часть.
+// Строка без числа считается нулем
+func leadingNumber(s string) float64 {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	end := 0
+	if end < len(s) && s[end] == '-' {
+		end++
+	}
+	digits, dot := 0, false
+	for ; end < len(s); end++ {
+		if s[end] >= '0' && s[end] <= '9' {
+			digits++
+		} else if s[end] == '.' && !dot {
+			dot = true
+		} else {
+			break
+		}
+	}
+	if digits == 0 {
+		return 0
+	}
+	n, _ := strconv.ParseFloat(strings.TrimSuffix(s[:end], "."), 64)
+	return n
+}
+
+func (s *Shell) sortCommand(args []string) {
+	// Сортирует строки файлов или стандартного ввода
+	opts, files, ok := s.parseArgs("sort", args)
+	if !ok {
+		return
+	}
+	separator := ""
+	if opts.Has("t") {
+		separator = opts.Value("t")
+		if len([]rune(separator)) != 1 {
+			s.errorf("sort: the separator must be a single character\n")
+			s.status = 2
+			return
+		}
+	}
+	var keys []sortKey
+	for _, def := range opts.Values("k") {
+		key, err := parseSortKey(def)
+		if err != nil {
+			s.errorf("sort: %v\n", err)
+			s.status = 2
+			return
+		}
+		// Глобальные -n и -r действуют на ключи без собственных флагов
+		if !key.numeric && !key.reverse {
+			key.numeric, key.reverse = opts.Has("n"), opts.Has("r")
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		keys = []sortKey{{start: 1, numeric: opts.Has("n"), reverse: opts.Has("r")}}
+	}
+
+	var lines []string
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, file := range files {
+		content, ok := s.readInput("sort", file)
+		if !ok {
+			return
+		}
+		lines = append(lines, splitLines(content)...)
+	}
+
+	// Поля строки: через разделитель -t или по группам пробелов
+	fields := func(line string) []string {
+		if separator != "" {
+			return strings.Split(line, separator)
+		}
+		return strings.Fields(line)
+	}
+	join := separator
+	if join == "" {
+		join = " "
+	}
+	keyText := func(line string, key sortKey) string {
+		f := fields(line)
+		if key.start == 1 && key.end == 0 && separator == "" {
+			return line // ключ по умолчанию - вся строка
+		}
+		if key.start > len(f) {
+			return ""
+		}
+		end := len(f)
+		if key.end > 0 {
+			end = min(key.end, len(f))
+		}
+		return strings.Join(f[key.start-1:end], join)
+	}
+	compareKeys := func(a, b string) int {
+		for _, key := range keys {
+			ka, kb := keyText(a, key), keyText(b, key)
+			var c int
+			if key.numeric {
+				c = cmp.Compare(leadingNumber(ka), leadingNumber(kb))
+			} else {
+				c = strings.Compare(ka, kb)
+			}
+			if key.reverse {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+	slices.SortStableFunc(lines, func(a, b string) int {
+		if c := compareKeys(a, b); c != 0 || opts.Has("u") {
+			return c
+		}
+		// При равных ключах строки сравниваются целиком, как в GNU sort
+		c := strings.Compare(a, b)
+		if opts.Has("r") {
+			c = -c
+		}
+		return c
+	})
+	// -u: из строк с равными ключами выводится первая
+	if opts.Has("u") {
+		lines = slices.CompactFunc(lines, func(a, b string) bool { return compareKeys(a, b) == 0 })
+	}
+	file := ""
+	if len(files) == 1 && files[0] != "-" {
+		file = s.absPath(files[0])
+	}
+	s.writeLines("sort", file, opts.Value("o"), lines)
+}
+
+// Диапазон списка cut: номера с 1, to = 0 - до конца строки
+type cutRange struct {
+	from, to int
+}
+
+// Разбирает список cut вида N, N-M, N-, -M через запятую
+func parseCutList(list string) ([]cutRange, error) {
+	var ranges []cutRange
+	for _, item := range strings.Split(list, ",") {
+		fromText, toText, isRange := strings.Cut(item, "-")
+		r := cutRange{from: 1}
+		var err error
+		if fromText != "" {
+			if r.from, err = strconv.Atoi(fromText); err != nil || r.from < 1 {
+				return nil, fmt.Errorf("invalid field value '%s'", item)
+			}
+		}
+		switch {
+		case !isRange:
+			r.to = r.from
+		case toText != "":
+			if r.to, err = strconv.Atoi(toText); err != nil || r.to < r.from {
+				return nil, fmt.Errorf("invalid field range '%s'", item)
+			}
+		case fromText == "":
+			return nil, fmt.Errorf("invalid range with no endpoint: -")
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// Входит ли номер n в один из диапазонов
+func cutSelected(ranges []cutRange, n int) bool {
+	for _, r := range ranges {
+		if n >= r.from && (r.to == 0 || n <= r.to) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Shell) cutCommand(args []string) {
+	// Выводит выбранные поля или символы каждой строки
+	opts, files, ok := s.parseArgs("cut", args)
+	if !ok {
+		return
+	}
+	switch {
+	case opts.Has("f") && opts.Has("c"):
+		s.errorf("cut: only one type of list may be specified\n")
+		s.status = 2
+		return
+	case !opts.Has("f") && !opts.Has("c"):
+		s.errorf("cut: you must specify a list of characters or fields\n")
+		s.status = 2
+		return
+	}
+	delimiter := "\t"
+	if opts.Has("d") {
+		if opts.Has("c") {
+			s.errorf("cut: an input delimiter may be specified only when operating on fields\n")
+			s.status = 2
+			return
+		}
+		delimiter = opts.Value("d")
+		if len([]rune(delimiter)) != 1 {
+			s.errorf("cut: the delimiter must be a single character\n")
+			s.status = 2
+			return
+		}
+	}
+	list := opts.Value("f")
+	if opts.Has("c") {
+		list = opts.Value("c")
+	}
+	ranges, err := parseCutList(list)
+	if err != nil {
+		s.errorf("cut: %v\n", err)
+		s.status = 2
+		return
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	out, flush := s.streamOut()
+	defer flush()
+	for _, file := range files {
+		input, ok := s.openInput("cut", file)
+		if !ok {
+			continue
+		}
+		for input.scan() {
+			line := input.line
+			var selected []string
+			switch {
+			case opts.Has("c"):
+				for i, r := range []rune(line) {
+					if cutSelected(ranges, i+1) {
+						selected = append(selected, string(r))
+					}
+				}
+				io.WriteString(out, strings.Join(selected, "")+"\n")
+				continue
+			case !strings.Contains(line, delimiter):
+				// Строки без разделителя выводятся целиком, с -s - пропускаются
+				if !opts.Has("s") {
+					io.WriteString(out, line+"\n")
+				}
+				continue
+			}
+			for i, field := range strings.Split(line, delimiter) {
+				if cutSelected(ranges, i+1) {
+					selected = append(selected, field)
+				}
+			}
+			io.WriteString(out, strings.Join(selected, delimiter)+"\n")
+		}
+		input.close()
+		if input.err != nil {
+			s.errorf("cut: %v\n", input.err)
+		}
+	}
+}
+
+// Классы символов tr
+var trClasses = map[string]func(rune) bool{
+	"alnum": func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) },
+	"alpha": unicode.IsLetter,
+	"blank": func(r rune) bool { return r == ' ' || r == '\t' },
+	"digit": unicode.IsDigit,
+	"lower": unicode.IsLower,
+	"punct": unicode.IsPunct,
+	"space": unicode.IsSpace,
+	"upper": unicode.IsUpper,
+}
+
+// Раскрывает набор символов tr: диапазоны a-z, классы [:upper:], экранирование \n, \t, \\
+func parseTrSet(set string) ([]rune, error) {
+	var chars []rune
+	runes := []rune(set)
+	for i := 0; i < len(runes); i++ {
+		if rest := string(runes[i:]); strings.HasPrefix(rest, "[:") {
+			if end := strings.Index(rest, ":]"); end > 0 {
+				class, ok := trClasses[rest[2:end]]
+				if !ok {
+					return nil, fmt.Errorf("invalid character class '%s'", rest[2:end])
+				}
+				// Классы перечисляются в пределах ASCII, как в локали C
+				for r := rune(0); r < 128; r++ {
+					if class(r) {
+						chars = append(chars, r)
+					}
+				}
+				i += len([]rune(rest[:end+2])) - 1
+				continue
+			}
+		}
+		c := runes[i]
+		if c == '\\' && i+1 < len(runes) {
+			i++
+			switch runes[i] {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'r':
+				c = '\r'
+			default:
+				c = runes[i]
+			}
+		}
+		if i+2 < len(runes) && runes[i+1] == '-' {
+			end := runes[i+2]
+			if end < c {
+				return nil, fmt.Errorf("range-endpoints of '%c-%c' are in reverse collating sequence order", c, end)
+			}
+			for r := c; r <= end; r++ {
+				chars = append(chars, r)
+			}
+			i += 2
+			continue
+		}
+		chars = append(chars, c)
+	}
+	return chars, nil
+}
+
+func (s *Shell) trCommand(args []string) {
+	// Заменяет, удаляет или сжимает повторы символов стандартного ввода
+	opts, sets, ok := s.parseArgs("tr", args)
+	if !ok {
+		return
+	}
+	deleting, squeezing := opts.Has("d"), opts.Has("s")
+	switch {
+	case len(sets) == 0:
+		s.errorf("tr: missing operand\n")
+		s.status = 2
+		return
+	case len(sets) == 1 && !deleting && !squeezing, len(sets) == 1 && deleting && squeezing:
+		s.errorf("tr: missing operand after '%s'\n", sets[0])
+		s.status = 2
+		return
+	case len(sets) > 2 || len(sets) == 2 && deleting && !squeezing:
+		s.errorf("tr: extra operand '%s'\n", sets[len(sets)-1])
+		s.status = 2
+		return
+	}
+	set1, err := parseTrSet(sets[0])
+	if err != nil {
+		s.errorf("tr: %v\n", err)
+		s.status = 2
+		return
+	}
+	var set2 []rune
+	if len(sets) == 2 {
+		if set2, err = parseTrSet(sets[1]); err != nil {
+			s.errorf("tr: %v\n", err)
+			s.status = 2
+			return
+		}
+	}
+	translate := map[rune]rune{}
+	deleted := map[rune]bool{}
+	squeezed := map[rune]bool{}
+	switch {
+	case deleting:
+		for _, r := range set1 {
+			deleted[r] = true
+		}
+	case len(set2) > 0:
+		// Короткий SET2 дополняется своим последним символом
+		for i, r := range set1 {
+			translate[r] = set2[min(i, len(set2)-1)]
+		}
+	}
+	// Сжимаются символы последнего набора
+	squeezeSet := set1
+	if len(sets) == 2 {
+		squeezeSet = set2
+	}
+	if squeezing {
+		for _, r := range squeezeSet {
+			squeezed[r] = true
+		}
+	}
+	input, ok := s.openInput("tr", "-")
+	if !ok {
+		return
+	}
+	defer input.close()
+	out, flush := s.streamOut()
+	defer flush()
+	// Ввод обрабатывается построчно; перевод строки - такой же символ, как остальные,
+	// и сжатие повторов продолжается через границу строк
+	prev, hasPrev := rune(0), false
+	for input.scan() {
+		var b strings.Builder
+		text := input.line
+		if input.newline {
+			text += "\n"
+		}
+		for _, r := range text {
+			if deleted[r] {
+				continue
+			}
+			if t, ok := translate[r]; ok {
+				r = t
+			}
+			if squeezed[r] && hasPrev && prev == r {
+				continue
+			}
+			b.WriteRune(r)
+			prev, hasPrev = r, true
+		}
+		io.WriteString(out, b.String())
+	}
+	if input.err != nil {
+		s.errorf("tr: %v\n", input.err)
+	}
+}
+
+func (s *Shell) teeCommand(args []string) {
+	// Копирует стандартный ввод в стандартный вывод и в файлы VFS
+	opts, files, ok := s.parseArgs("tee", args)
+	if !ok {
+		return
+	}
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if opts.Has("a") {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	outputs := make([]*vfs.File, len(files))
+	for i, name := range files {
+		f, err := s.vfs.OpenFile(s.absPath(name), flag)
+		if err != nil {
+			s.pathError("tee", name, err)
+			continue
+		}
+		defer f.Close()
+		outputs[i] = f
+	}
+	input, ok := s.openInput("tee", "-")
+	if !ok {
+		return
+	}
+	defer input.close()
+	out, flush := s.streamOut()
+	defer flush()
+	// Каждая строка сразу дописывается в файлы, так что tail -f видит ее до конца ввода
+	for input.scan() {
+		text := input.line
+		if input.newline {
+			text += "\n"
+		}
+		io.WriteString(out, text)
+		for i, f := range outputs {
+			if f == nil {
+				continue
+			}
+			if _, err := f.WriteString(text); err != nil {
+				s.pathError("tee", files[i], err)
+				outputs[i] = nil
+			}
+		}
+	}
+	if input.err != nil {
+		s.errorf("tee: %v\n", input.err)
+	}
+}
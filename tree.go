@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/TimofeyChernyshev/MIREA-Configuration-management-1/vfs"
+)
+
+func (s *Shell) treeCommand(args []string) {
+	// Выводит содержимое каталогов в виде дерева
+	opts, paths, ok := s.parseArgs("tree", args)
+	if !ok {
+		return
+	}
+	maxLevel := -1
+	if opts.Has("L") {
+		n, err := strconv.Atoi(opts.Value("L"))
+		if err != nil || n <= 0 {
+			s.errorf("tree: Invalid level, must be greater than 0.\n")
+			return
+		}
+		maxLevel = n
+	}
+	// Дочерние узлы, которые выводятся: по имени, без скрытых (кроме -a) и файлов (с -d)
+	visible := func(node *vfs.VFSNode) []*vfs.VFSNode {
+		var children []*vfs.VFSNode
+		for _, child := range node.Children {
+			if strings.HasPrefix(child.Name, ".") && !opts.Has("a") || opts.Has("d") && !child.IsDir {
+				continue
+			}
+			children = append(children, child)
+		}
+		slices.SortFunc(children, func(a, b *vfs.VFSNode) int { return strings.Compare(a.Name, b.Name) })
+		return children
+	}
+	// Колонки владельца и размера перед именем: [owner      size]
+	columns := func(node *vfs.VFSNode) string {
+		var fields []string
+		if opts.Has("u") {
+			owner := node.Owner
+			if owner == "" {
+				owner = "-"
+			}
+			fields = append(fields, fmt.Sprintf("%-8s", owner))
+		}
+		switch {
+		case opts.Has("h"):
+			fields = append(fields, fmt.Sprintf("%4s", humanSize(int64(len(node.Content)))))
+		case opts.Has("s"):
+			fields = append(fields, fmt.Sprintf("%11d", len(node.Content)))
+		}
+		if len(fields) == 0 {
+			return ""
+		}
+		return "[" + strings.Join(fields, " ") + "]  "
+	}
+	dirs, files := 0, 0
+	var build func(node *vfs.VFSNode, level int) treeEntry
+	build = func(node *vfs.VFSNode, level int) treeEntry {
+		entry := treeEntry{Name: node.Name, Type: nodeType(node.IsDir), Size: len(node.Content), Owner: node.Owner}
+		if !node.IsDir || maxLevel >= 0 && level >= maxLevel {
+			return entry
+		}
+		for _, child := range visible(node) {
+			if child.IsDir {
+				dirs++
+			} else {
+				files++
+			}
+			entry.Children = append(entry.Children, build(child, level+1))
+		}
+		return entry
+	}
+	var draw func(entry treeEntry, node *vfs.VFSNode, prefix string)
+	draw = func(entry treeEntry, node *vfs.VFSNode, prefix string) {
+		children := visible(node)
+		for i, child := range entry.Children {
+			branch, indent := "├── ", "│   "
+			if i == len(entry.Children)-1 {
+				branch, indent = "└── ", "    "
+			}
+			fmt.Fprintf(s.out(), "%s%s%s%s\n", prefix, branch, columns(children[i]), child.Name)
+			draw(child, children[i], prefix+indent)
+		}
+	}
+
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	for _, p := range paths {
+		node, err := s.vfs.FindNode(s.absPath(p))
+		if err != nil {
+			s.pathError("tree", p, err)
+			continue
+		}
+		entry := build(node, 0)
+		entry.Name = p
+		if !node.IsDir {
+			files++
+		}
+		if s.emit(entry) {
+			continue
+		}
+		fmt.Fprintf(s.out(), "%s%s\n", columns(node), p)
+		draw(entry, node, "")
+	}
+	if s.recording() {
+		return
+	}
+	summary := plural(dirs, "directory", "directories")
+	if !opts.Has("d") {
+		summary += ", " + plural(files, "file", "files")
+	}
+	fmt.Fprintf(s.out(), "\n%s\n", summary)
+}
+
+// Число с существительным в нужной форме: 1 file, 2 files
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, one)
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
@@ -0,0 +1,59 @@
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// Ошибка операции с путем: операция, путь и причина.
+// Совпадает с fs.PathError, поэтому ошибки VFS можно проверять так же, как ошибки os и io/fs
+type PathError = fs.PathError
+
+// Вид ошибки VFS. Сообщения совпадают с сообщениями утилит GNU
+type kindError struct {
+	msg    string
+	target error // соответствующая ошибка io/fs для errors.Is
+}
+
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+func (e *kindError) Is(target error) bool {
+	return e.target != nil && target == e.target
+}
+
+// Причины ошибок VFS. Проверяются через errors.Is; ErrNotExist, ErrExist и ErrPermission
+// также совпадают с fs.ErrNotExist, fs.ErrExist и fs.ErrPermission
+var (
+	ErrNotExist   error = &kindError{"No such file or directory", fs.ErrNotExist}
+	ErrExist      error = &kindError{"File exists", fs.ErrExist}
+	ErrPermission error = &kindError{"Permission denied", fs.ErrPermission}
+	ErrNotDir     error = &kindError{"Not a directory", nil}
+	ErrIsDir      error = &kindError{"Is a directory", nil}
+	ErrNotEmpty   error = &kindError{"Directory not empty", nil}
+
+	// Превышение ограничений VFS (см. Limits), аналоги ENOSPC, EDQUOT, EFBIG и ENAMETOOLONG
+	ErrNoSpace      error = &kindError{"No space left on device", nil}
+	ErrQuota        error = &kindError{"Disk quota exceeded", nil}
+	ErrFileTooLarge error = &kindError{"File too large", nil}
+	ErrNameTooLong  error = &kindError{"File name too long", nil}
+)
+
+// Причина ошибки без операции и пути: для *PathError - вложенная ошибка
+func Reason(err error) error {
+	var pathErr *PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	return err
+}
+
+// Заменяет операцию в ошибке поиска на операцию, при которой она возникла
+func withOp(op string, err error) error {
+	var pathErr *PathError
+	if errors.As(err, &pathErr) {
+		return &PathError{Op: op, Path: pathErr.Path, Err: pathErr.Err}
+	}
+	return err
+}
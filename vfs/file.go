@@ -0,0 +1,300 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Открытый файл или каталог VFS. Чтение и запись работают напрямую с содержимым узла,
+// поэтому изменения сразу видны другим дескрипторам и командам оболочки (например, tail -f).
+// Чтение, запись и изменение размера через File можно выполнять из разных горутин.
+// Open (fs.FS) возвращает *File только для чтения, OpenFile и Create - с нужными флагами
+type File struct {
+	vfs     *VFS
+	name    string // путь, по которому файл открыт
+	node    *VFSNode
+	flag    int   // флаги открытия os.O_*
+	offset  int64 // текущая позиция чтения/записи
+	entries []fs.DirEntry
+	closed  bool
+}
+
+var (
+	_ fs.File            = (*File)(nil)
+	_ fs.ReadDirFile     = (*File)(nil)
+	_ io.ReadWriteSeeker = (*File)(nil)
+	_ io.ReaderAt        = (*File)(nil)
+	_ io.WriterAt        = (*File)(nil)
+)
+
+// Создает файл или обрезает существующий до нулевой длины и открывает его для чтения и записи
+func (v *VFS) Create(name string) (*File, error) {
+	return v.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC)
+}
+
+// Открывает файл с флагами os.O_*: O_RDONLY, O_WRONLY или O_RDWR, а также O_APPEND (запись в конец),
+// O_CREATE (создать, если нет), O_EXCL (вместе с O_CREATE - файл не должен существовать)
+// и O_TRUNC (обрезать до нулевой длины). Путь задается как в командах оболочки: "/a/b" или "a/b" от корня
+func (v *VFS) OpenFile(name string, flag int) (*File, error) {
+	node, err := v.FindNode(name)
+	switch {
+	case err == nil:
+		if flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0 {
+			return nil, &PathError{Op: "open", Path: name, Err: ErrExist}
+		}
+	case flag&os.O_CREATE != 0 && errors.Is(err, ErrNotExist):
+		if node, err = v.createFile(name); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, withOp("open", err)
+	}
+	f := &File{vfs: v, name: name, node: node, flag: flag}
+	if node.IsDir {
+		if f.writable() {
+			return nil, &PathError{Op: "open", Path: name, Err: ErrIsDir}
+		}
+		f.entries = dirEntries(node)
+		return f, nil
+	}
+	if flag&os.O_TRUNC != 0 && f.writable() {
+		v.mu.Lock()
+		if node.Content != "" {
+			v.usage().resize(node, -int64(len(node.Content)))
+			node.Content = ""
+			node.touchContent()
+		}
+		v.mu.Unlock()
+	}
+	return f, nil
+}
+
+// Создает пустой файл в существующем каталоге. Владелец файла - текущий пользователь VFS,
+// а если он не задан - владелец каталога, так что квота владельца действует и для новых файлов
+func (v *VFS) createFile(name string) (*VFSNode, error) {
+	parent, err := v.FindNode(getParentPath(name))
+	if err != nil {
+		return nil, withOp("open", err)
+	}
+	if !parent.IsDir {
+		return nil, &PathError{Op: "open", Path: name, Err: ErrNotDir}
+	}
+	nodeName := getNameFromPath(name)
+	if err := v.checkPath("open", name, nodeName, pathDepth(name)); err != nil {
+		return nil, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.checkSpace("open", name, 1, 0); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	owner := v.User
+	if owner == "" {
+		owner = parent.Owner
+	}
+	node := &VFSNode{Name: nodeName, Owner: owner, ModTime: now, ATime: now, CTime: now, BirthTime: now}
+	// Итоги берутся до изменения дерева: при первом обращении они считаются обходом
+	usage := v.usage()
+	parent.Children = append(parent.Children, node)
+	parent.ModTime = now
+	usage.add(node, 1)
+	return node, nil
+}
+
+func (f *File) writable() bool {
+	return f.flag&(os.O_WRONLY|os.O_RDWR) != 0
+}
+
+func (f *File) readable() bool {
+	return f.flag&os.O_WRONLY == 0
+}
+
+func (f *File) checkOpen(op string) error {
+	if f.closed {
+		return &PathError{Op: op, Path: f.name, Err: fs.ErrClosed}
+	}
+	return nil
+}
+
+// Путь, по которому файл открыт
+func (f *File) Name() string {
+	return f.name
+}
+
+func (f *File) Stat() (fs.FileInfo, error) {
+	if err := f.checkOpen("stat"); err != nil {
+		return nil, err
+	}
+	return &fileInfo{name: path.Base(f.name), node: f.node}, nil
+}
+
+func (f *File) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.checkOpen("read"); err != nil {
+		return 0, err
+	}
+	f.vfs.mu.Lock()
+	defer f.vfs.mu.Unlock()
+	if f.node.IsDir {
+		return 0, &PathError{Op: "read", Path: f.name, Err: ErrIsDir}
+	}
+	if !f.readable() {
+		return 0, &PathError{Op: "read", Path: f.name, Err: ErrPermission}
+	}
+	if off < 0 {
+		return 0, &PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if off >= int64(len(f.node.Content)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.Content[off:])
+	f.node.ATime = time.Now()
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Записывает данные с текущей позиции, а в режиме O_APPEND - в конец файла
+func (f *File) Write(p []byte) (int, error) {
+	f.vfs.mu.Lock()
+	defer f.vfs.mu.Unlock()
+	// Конец файла определяется под блокировкой, чтобы дозапись из разных горутин не перекрывалась
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = int64(len(f.node.Content))
+	}
+	n, err := f.writeAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *File) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+// Записывает данные с позиции off. Промежуток за концом файла заполняется нулевыми байтами
+func (f *File) WriteAt(p []byte, off int64) (int, error) {
+	f.vfs.mu.Lock()
+	defer f.vfs.mu.Unlock()
+	return f.writeAt(p, off)
+}
+
+func (f *File) writeAt(p []byte, off int64) (int, error) {
+	if err := f.checkOpen("write"); err != nil {
+		return 0, err
+	}
+	if !f.writable() {
+		return 0, &PathError{Op: "write", Path: f.name, Err: ErrPermission}
+	}
+	if off < 0 {
+		return 0, &PathError{Op: "write", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if err := f.vfs.checkResize("write", f.name, f.node, max(int64(len(f.node.Content)), off+int64(len(p)))); err != nil {
+		return 0, err
+	}
+	content := f.node.Content
+	if gap := off - int64(len(content)); gap > 0 {
+		content += strings.Repeat("\x00", int(gap))
+	}
+	end := off + int64(len(p))
+	tail := ""
+	if end < int64(len(content)) {
+		tail = content[end:]
+	}
+	updated := content[:off] + string(p) + tail
+	f.vfs.usage().resize(f.node, int64(len(updated)-len(f.node.Content)))
+	f.node.Content = updated
+	f.node.touchContent()
+	return len(p), nil
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	if err := f.checkOpen("seek"); err != nil {
+		return 0, err
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		// Размер читается под блокировкой: файл могут менять через другие дескрипторы
+		f.vfs.mu.Lock()
+		offset += int64(len(f.node.Content))
+		f.vfs.mu.Unlock()
+	}
+	if offset < 0 {
+		return 0, &PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Изменяет размер файла: обрезает его или дополняет нулевыми байтами
+func (f *File) Truncate(size int64) error {
+	if err := f.checkOpen("truncate"); err != nil {
+		return err
+	}
+	if !f.writable() {
+		return &PathError{Op: "truncate", Path: f.name, Err: ErrPermission}
+	}
+	if size < 0 {
+		return &PathError{Op: "truncate", Path: f.name, Err: fs.ErrInvalid}
+	}
+	f.vfs.mu.Lock()
+	defer f.vfs.mu.Unlock()
+	if err := f.vfs.checkResize("truncate", f.name, f.node, size); err != nil {
+		return err
+	}
+	f.vfs.usage().resize(f.node, size-int64(len(f.node.Content)))
+	if size <= int64(len(f.node.Content)) {
+		f.node.Content = f.node.Content[:size]
+	} else {
+		f.node.Content += strings.Repeat("\x00", int(size)-len(f.node.Content))
+	}
+	f.node.touchContent()
+	return nil
+}
+
+// Возвращает следующие n записей каталога или все оставшиеся при n <= 0.
+// Записи берутся из снимка на момент открытия и отсортированы по имени
+func (f *File) ReadDir(n int) ([]fs.DirEntry, error) {
+	if err := f.checkOpen("readdir"); err != nil {
+		return nil, err
+	}
+	if !f.node.IsDir {
+		return nil, &PathError{Op: "readdir", Path: f.name, Err: ErrNotDir}
+	}
+	rest := f.entries[f.offset:]
+	if n <= 0 {
+		f.offset = int64(len(f.entries))
+		return slices.Clone(rest), nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	f.offset += int64(n)
+	return slices.Clone(rest[:n]), nil
+}
+
+func (f *File) Close() error {
+	if err := f.checkOpen("close"); err != nil {
+		return err
+	}
+	f.closed = true
+	return nil
+}
@@ -0,0 +1,115 @@
+package vfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// VFS реализует интерфейсы io/fs, поэтому с ней работают fs.WalkDir, fs.Glob,
+// template.ParseFS, http.FS и fstest.TestFS
+var (
+	_ fs.FS         = (*VFS)(nil)
+	_ fs.ReadDirFS  = (*VFS)(nil)
+	_ fs.StatFS     = (*VFS)(nil)
+	_ fs.ReadFileFS = (*VFS)(nil)
+)
+
+// Права доступа узлов по умолчанию
+const (
+	fileMode = 0o644
+	dirMode  = fs.ModeDir | 0o755
+)
+
+// Информация об узле для io/fs
+type fileInfo struct {
+	name string
+	node *VFSNode
+}
+
+func (fi *fileInfo) Name() string { return fi.name }
+func (fi *fileInfo) Size() int64  { return int64(len(fi.node.Content)) }
+func (fi *fileInfo) IsDir() bool  { return fi.node.IsDir }
+
+func (fi *fileInfo) Mode() fs.FileMode { return fi.node.FileMode() }
+
+func (fi *fileInfo) ModTime() time.Time { return fi.node.ModTime }
+
+// Возвращает сам узел VFS
+func (fi *fileInfo) Sys() any { return fi.node }
+
+// Находит узел по пути в формате io/fs: без ведущего "/", корень - "."
+func (v *VFS) lookup(op, name string) (*VFSNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return v.Root, nil
+	}
+	node, err := v.FindNode(name)
+	if err != nil {
+		return nil, withOp(op, err)
+	}
+	return node, nil
+}
+
+// Записи каталога, отсортированные по имени
+func dirEntries(node *VFSNode) []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(node.Children))
+	for _, child := range node.Children {
+		entries = append(entries, fs.FileInfoToDirEntry(&fileInfo{name: child.Name, node: child}))
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries
+}
+
+// Открывает файл или каталог только для чтения (fs.FS). Возвращаемый файл имеет тип *File
+func (v *VFS) Open(name string) (fs.File, error) {
+	node, err := v.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{vfs: v, name: name, node: node, flag: os.O_RDONLY}
+	if node.IsDir {
+		f.entries = dirEntries(node)
+	}
+	return f, nil
+}
+
+// Возвращает записи каталога, отсортированные по имени (fs.ReadDirFS)
+func (v *VFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, err := v.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !node.IsDir {
+		return nil, &PathError{Op: "readdir", Path: name, Err: ErrNotDir}
+	}
+	return dirEntries(node), nil
+}
+
+// Возвращает информацию о файле или каталоге (fs.StatFS)
+func (v *VFS) Stat(name string) (fs.FileInfo, error) {
+	node, err := v.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{name: path.Base(name), node: node}, nil
+}
+
+// Возвращает содержимое файла (fs.ReadFileFS)
+func (v *VFS) ReadFile(name string) ([]byte, error) {
+	node, err := v.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if node.IsDir {
+		return nil, &PathError{Op: "read", Path: name, Err: ErrIsDir}
+	}
+	return []byte(node.Content), nil
+}
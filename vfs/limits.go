@@ -0,0 +1,238 @@
+package vfs
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// Ограничения VFS. Нулевое значение поля означает отсутствие ограничения.
+// Проверяются при загрузке с диска и при каждом изменении дерева через методы VFS и File
+type Limits struct {
+	MaxBytes      int64            // суммарный размер содержимого файлов
+	MaxNodes      int              // число узлов, включая корень
+	MaxFileSize   int64            // размер одного файла
+	MaxDepth      int              // число элементов пути от корня
+	MaxNameLength int              // длина имени файла или каталога в байтах
+	OwnerQuotas   map[string]int64 // суммарный размер файлов каждого владельца
+}
+
+// Занятое место в VFS
+type Usage struct {
+	Bytes int64 // суммарный размер содержимого файлов
+	Nodes int   // число узлов, включая корень
+	Files int
+	Dirs  int
+}
+
+// Место, занятое файлами одного владельца
+type OwnerUsage struct {
+	Bytes int64
+	Files int
+}
+
+// Подсчитывает занятое место во всем дереве
+func (v *VFS) Usage() Usage {
+	var usage Usage
+	if v.Root == nil {
+		return usage
+	}
+	v.walk(v.Root, func(n *VFSNode) {
+		usage.Nodes++
+		usage.Bytes += int64(len(n.Content))
+		if n.IsDir {
+			usage.Dirs++
+		} else {
+			usage.Files++
+		}
+	})
+	return usage
+}
+
+// Подсчитывает место, занятое файлами каждого владельца. Файлы без владельца не учитываются
+func (v *VFS) OwnerUsage() map[string]OwnerUsage {
+	owners := map[string]OwnerUsage{}
+	if v.Root == nil {
+		return owners
+	}
+	v.walk(v.Root, func(n *VFSNode) {
+		if n.Owner == "" || n.IsDir {
+			return
+		}
+		usage := owners[n.Owner]
+		usage.Bytes += int64(len(n.Content))
+		usage.Files++
+		owners[n.Owner] = usage
+	})
+	return owners
+}
+
+// Занятое место, по которому проверяются ограничения. Итоги поддерживаются при каждом
+// изменении дерева через методы VFS и File, поэтому проверка не обходит все дерево
+type totals struct {
+	root   *VFSNode // корень, для которого посчитаны итоги
+	nodes  int
+	bytes  int64
+	owners map[string]int64 // размер файлов каждого владельца
+}
+
+// Пересчитывает итоги обходом дерева. Нужен, если узлы добавлены или изменены
+// напрямую через поля VFSNode, а не через методы VFS и File
+func (v *VFS) Recount() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.recount()
+}
+
+func (v *VFS) recount() {
+	v.totals = totals{root: v.Root, owners: map[string]int64{}}
+	if v.Root != nil {
+		v.walk(v.Root, func(n *VFSNode) { v.totals.add(n, 1) })
+	}
+}
+
+// Итоги для текущего дерева: считаются при первом обращении и после замены корня.
+// Вызывается под блокировкой v.mu
+func (v *VFS) usage() *totals {
+	if v.totals.root != v.Root || v.totals.owners == nil {
+		v.recount()
+	}
+	return &v.totals
+}
+
+// Учитывает появление (sign = 1) или удаление (sign = -1) узла
+func (t *totals) add(n *VFSNode, sign int) {
+	t.nodes += sign
+	t.resize(n, int64(sign)*int64(len(n.Content)))
+}
+
+// Учитывает изменение размера содержимого узла на delta байт
+func (t *totals) resize(n *VFSNode, delta int64) {
+	t.bytes += delta
+	if !n.IsDir && n.Owner != "" {
+		t.owners[n.Owner] += delta
+	}
+}
+
+// Суммарный размер содержимого узла и всех его потомков
+func (n *VFSNode) DiskUsage() int64 {
+	size := int64(len(n.Content))
+	for _, child := range n.Children {
+		size += child.DiskUsage()
+	}
+	return size
+}
+
+// Высота поддерева: 0 для файла и пустого каталога
+func (n *VFSNode) height() int {
+	h := 0
+	for _, child := range n.Children {
+		h = max(h, child.height()+1)
+	}
+	return h
+}
+
+// Число элементов пути от корня: 0 для "/", 1 для "/a"
+func pathDepth(p string) int {
+	clean := strings.Trim(path.Clean("/"+p), "/")
+	if clean == "" {
+		return 0
+	}
+	return strings.Count(clean, "/") + 1
+}
+
+// Проверяет длину имени и глубину пути нового или перемещаемого узла
+func (v *VFS) checkPath(op, p, name string, depth int) error {
+	if v.Limits.MaxNameLength > 0 && len(name) > v.Limits.MaxNameLength {
+		return &PathError{Op: op, Path: p, Err: ErrNameTooLong}
+	}
+	if v.Limits.MaxDepth > 0 && depth > v.Limits.MaxDepth {
+		return &PathError{Op: op, Path: p, Err: ErrNameTooLong}
+	}
+	return nil
+}
+
+// Проверяет, что в VFS есть место для nodes новых узлов и bytes новых байт.
+// Здесь и в остальных проверках вызывающий удерживает v.mu
+func (v *VFS) checkSpace(op, p string, nodes int, bytes int64) error {
+	if v.Limits.MaxNodes <= 0 && v.Limits.MaxBytes <= 0 || nodes <= 0 && bytes <= 0 {
+		return nil
+	}
+	usage := v.usage()
+	if v.Limits.MaxNodes > 0 && nodes > 0 && usage.nodes+nodes > v.Limits.MaxNodes ||
+		v.Limits.MaxBytes > 0 && bytes > 0 && usage.bytes+bytes > v.Limits.MaxBytes {
+		return &PathError{Op: op, Path: p, Err: ErrNoSpace}
+	}
+	return nil
+}
+
+// Проверяет квоту владельца при добавлении bytes байт к его файлам
+func (v *VFS) checkQuota(op, p, owner string, bytes int64) error {
+	quota, ok := v.Limits.OwnerQuotas[owner]
+	if owner == "" || !ok || bytes <= 0 {
+		return nil
+	}
+	if v.usage().owners[owner]+bytes > quota {
+		return &PathError{Op: op, Path: p, Err: ErrQuota}
+	}
+	return nil
+}
+
+// Проверяет изменение размера файла node с текущего до size
+func (v *VFS) checkResize(op, p string, node *VFSNode, size int64) error {
+	if v.Limits.MaxFileSize > 0 && size > v.Limits.MaxFileSize {
+		return &PathError{Op: op, Path: p, Err: ErrFileTooLarge}
+	}
+	growth := size - int64(len(node.Content))
+	if err := v.checkSpace(op, p, 0, growth); err != nil {
+		return err
+	}
+	return v.checkQuota(op, p, node.Owner, growth)
+}
+
+// Меняет владельца узла с учетом квоты нового владельца
+func (v *VFS) Chown(p, owner string) error {
+	node, err := v.FindNode(p)
+	if err != nil {
+		return withOp("chown", err)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	// Файл, который переходит к новому владельцу, учитывается в его квоте
+	if node.Owner != owner {
+		if err := v.checkQuota("chown", p, owner, int64(len(node.Content))); err != nil {
+			return err
+		}
+	}
+	usage := v.usage()
+	usage.add(node, -1)
+	node.Owner = owner
+	node.CTime = time.Now()
+	usage.add(node, 1)
+	return nil
+}
+
+// Проверяет ограничения для узла, загружаемого с диска, и учитывает его в итогах загрузки:
+// дерево строится с нуля, поэтому обход не нужен
+func (v *VFS) checkLoad(hostPath, relPath string, node *VFSNode, size int64, usage *totals) error {
+	if err := v.checkPath("load", hostPath, node.Name, pathDepth(relPath)); err != nil {
+		return err
+	}
+	limits := v.Limits
+	if limits.MaxFileSize > 0 && size > limits.MaxFileSize {
+		return &PathError{Op: "load", Path: hostPath, Err: ErrFileTooLarge}
+	}
+	if limits.MaxNodes > 0 && usage.nodes+1 > limits.MaxNodes ||
+		limits.MaxBytes > 0 && usage.bytes+size > limits.MaxBytes {
+		return &PathError{Op: "load", Path: hostPath, Err: ErrNoSpace}
+	}
+	if quota, ok := limits.OwnerQuotas[node.Owner]; ok && node.Owner != "" && !node.IsDir && usage.owners[node.Owner]+size > quota {
+		return &PathError{Op: "load", Path: hostPath, Err: ErrQuota}
+	}
+	usage.nodes++
+	usage.bytes += size
+	if !node.IsDir {
+		usage.owners[node.Owner] += size
+	}
+	return nil
+}
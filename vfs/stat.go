@@ -0,0 +1,175 @@
+package vfs
+
+import (
+	"io/fs"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+)
+
+// Метаданные узла, как их возвращает stat(2)
+type StatInfo struct {
+	Path      string
+	Name      string
+	IsDir     bool
+	Size      int64
+	Mode      fs.FileMode // права доступа вместе с битом fs.ModeDir для каталогов
+	Owner     string
+	Group     string
+	Inode     uint64
+	Links     int // число жестких ссылок: 1 для файлов, 2 + число подкаталогов для каталогов
+	ATime     time.Time
+	MTime     time.Time
+	CTime     time.Time
+	BirthTime time.Time // нулевое значение, если время создания неизвестно
+}
+
+// Тип узла в формате stat: "regular file", "regular empty file" или "directory"
+func (st *StatInfo) Type() string {
+	switch {
+	case st.IsDir:
+		return "directory"
+	case st.Size == 0:
+		return "regular empty file"
+	}
+	return "regular file"
+}
+
+// Возвращает метаданные узла по пути в формате команд оболочки
+func (v *VFS) StatPath(path string) (*StatInfo, error) {
+	node, err := v.FindNode(path)
+	if err != nil {
+		return nil, withOp("stat", err)
+	}
+	st := &StatInfo{
+		Path:      path,
+		Name:      node.Name,
+		IsDir:     node.IsDir,
+		Size:      int64(len(node.Content)),
+		Mode:      node.FileMode(),
+		Owner:     node.Owner,
+		Group:     node.Group,
+		Inode:     v.inode(node),
+		Links:     1,
+		ATime:     node.AccessTime(),
+		MTime:     node.ModTime,
+		CTime:     node.ChangeTime(),
+		BirthTime: node.BirthTime,
+	}
+	if node.IsDir {
+		st.Links = 2
+		for _, child := range node.Children {
+			if child.IsDir {
+				st.Links++
+			}
+		}
+	}
+	return st, nil
+}
+
+// Права доступа узла вместе с битом fs.ModeDir для каталогов
+func (n *VFSNode) FileMode() fs.FileMode {
+	perm := n.Mode.Perm()
+	if n.IsDir {
+		if n.Mode == 0 {
+			perm = dirMode.Perm()
+		}
+		return fs.ModeDir | perm
+	}
+	if n.Mode == 0 {
+		perm = fileMode
+	}
+	return perm
+}
+
+// Время последнего доступа. Если оно не задано, совпадает со временем изменения
+func (n *VFSNode) AccessTime() time.Time {
+	if n.ATime.IsZero() {
+		return n.ModTime
+	}
+	return n.ATime
+}
+
+// Время изменения метаданных. Если оно не задано, совпадает со временем изменения
+func (n *VFSNode) ChangeTime() time.Time {
+	if n.CTime.IsZero() {
+		return n.ModTime
+	}
+	return n.CTime
+}
+
+// Отмечает изменение содержимого узла: обновляет время изменения данных и метаданных
+func (n *VFSNode) touchContent() {
+	n.ModTime = time.Now()
+	n.CTime = n.ModTime
+}
+
+// Номер узла. Назначается при первом обращении, уникален в пределах VFS
+func (v *VFS) inode(node *VFSNode) uint64 {
+	if node.Inode != 0 {
+		return node.Inode
+	}
+	if v.lastInode == 0 {
+		// Номера могли быть сохранены ранее - продолжаем с максимального
+		v.walk(v.Root, func(n *VFSNode) {
+			v.lastInode = max(v.lastInode, n.Inode)
+		})
+	}
+	v.lastInode++
+	node.Inode = v.lastInode
+	return node.Inode
+}
+
+// Обходит узел и всех его потомков
+func (v *VFS) walk(node *VFSNode, visit func(*VFSNode)) {
+	visit(node)
+	for _, child := range node.Children {
+		v.walk(child, visit)
+	}
+}
+
+// Заполняет метаданные узла по файлу на диске хоста
+func (n *VFSNode) setHostMetadata(info os.FileInfo, names *idNames) {
+	n.ModTime = info.ModTime()
+	n.Mode = info.Mode().Perm()
+	n.ATime, n.CTime, n.BirthTime = hostTimes(info)
+	n.Owner, n.Group = hostOwner(info, names)
+}
+
+// Имена пользователей и групп по числовым идентификаторам хоста.
+// Кэшируются, чтобы не обращаться к системе для каждого файла при загрузке
+type idNames struct {
+	users  map[uint32]string
+	groups map[uint32]string
+}
+
+func newIDNames() *idNames {
+	return &idNames{users: map[uint32]string{}, groups: map[uint32]string{}}
+}
+
+// Имя пользователя по uid или сам uid, если пользователь не найден
+func (c *idNames) user(uid uint32) string {
+	name, ok := c.users[uid]
+	if !ok {
+		name = strconv.FormatUint(uint64(uid), 10)
+		if u, err := user.LookupId(name); err == nil {
+			name = u.Username
+		}
+		c.users[uid] = name
+	}
+	return name
+}
+
+// Имя группы по gid или сам gid, если группа не найдена
+func (c *idNames) group(gid uint32) string {
+	name, ok := c.groups[gid]
+	if !ok {
+		name = strconv.FormatUint(uint64(gid), 10)
+		if g, err := user.LookupGroupId(name); err == nil {
+			name = g.Name
+		}
+		c.groups[gid] = name
+	}
+	return name
+}
@@ -0,0 +1,26 @@
+//go:build linux || openbsd || dragonfly || solaris
+
+package vfs
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Время доступа и изменения метаданных файла хоста. Время создания в stat(2) не передается
+func hostTimes(info os.FileInfo) (atime, ctime, btime time.Time) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.ModTime(), info.ModTime(), time.Time{}
+	}
+	return time.Unix(st.Atim.Unix()), time.Unix(st.Ctim.Unix()), time.Time{}
+}
+
+func hostOwner(info os.FileInfo, names *idNames) (string, string) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", ""
+	}
+	return names.user(st.Uid), names.group(st.Gid)
+}
@@ -0,0 +1,26 @@
+//go:build darwin || freebsd || netbsd
+
+package vfs
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Время доступа, изменения метаданных и создания файла хоста
+func hostTimes(info os.FileInfo) (atime, ctime, btime time.Time) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.ModTime(), info.ModTime(), time.Time{}
+	}
+	return time.Unix(st.Atimespec.Unix()), time.Unix(st.Ctimespec.Unix()), time.Unix(st.Birthtimespec.Unix())
+}
+
+func hostOwner(info os.FileInfo, names *idNames) (string, string) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", ""
+	}
+	return names.user(st.Uid), names.group(st.Gid)
+}
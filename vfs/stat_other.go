@@ -0,0 +1,17 @@
+//go:build !linux && !openbsd && !dragonfly && !solaris && !darwin && !freebsd && !netbsd && !windows
+
+package vfs
+
+import (
+	"os"
+	"time"
+)
+
+// На остальных системах известно только время изменения
+func hostTimes(info os.FileInfo) (atime, ctime, btime time.Time) {
+	return info.ModTime(), info.ModTime(), time.Time{}
+}
+
+func hostOwner(info os.FileInfo, names *idNames) (string, string) {
+	return "", ""
+}
@@ -0,0 +1,22 @@
+package vfs
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Время доступа и создания файла хоста. Отдельного времени изменения метаданных
+// в Windows нет, вместо него используется время последней записи
+func hostTimes(info os.FileInfo) (atime, ctime, btime time.Time) {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return info.ModTime(), info.ModTime(), time.Time{}
+	}
+	return time.Unix(0, data.LastAccessTime.Nanoseconds()), info.ModTime(), time.Unix(0, data.CreationTime.Nanoseconds())
+}
+
+// Владелец файла в Windows задается дескриптором безопасности и не переносится в VFS
+func hostOwner(info os.FileInfo, names *idNames) (string, string) {
+	return "", ""
+}
@@ -1,10 +1,11 @@
 package vfs
 
 import (
-	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -16,12 +17,25 @@ type VFSNode struct {
 	Children []*VFSNode `json:"children,omitempty"` // Дочерние узлы (для папок)
 	ModTime  time.Time  `json:"modTime"`            // Время последнего изменения
 	Owner    string     `json:"owner,omitempty"`    // Владелец файла
+
+	Group     string      `json:"group,omitempty"`    // Группа владельца
+	Mode      fs.FileMode `json:"mode,omitempty"`     // Права доступа, 0 - по умолчанию (0644 или 0755)
+	Inode     uint64      `json:"inode,omitempty"`    // Номер узла, назначается при первом обращении
+	ATime     time.Time   `json:"atime,omitzero"`     // Время последнего доступа
+	CTime     time.Time   `json:"ctime,omitzero"`     // Время изменения метаданных
+	BirthTime time.Time   `json:"birthTime,omitzero"` // Время создания, если известно
 }
 
 // Виртуальная файловая система
 type VFS struct {
 	Root     *VFSNode `json:"root"` // Корневой узел
 	IsLoaded bool     `json:"-"`    // Загружена ли VFS в память
+	Limits   Limits   `json:"-"`    // Ограничения на размер дерева
+	User     string   `json:"-"`    // Владелец создаваемых файлов; пусто - владелец родительского каталога
+
+	lastInode uint64     // последний назначенный номер узла
+	mu        sync.Mutex // защищает содержимое файлов при чтении и записи через File из разных горутин и итоги
+	totals    totals     // занятое место для проверки ограничений
 }
 
 func (v *VFS) LoadFromDisk(path string) error {
@@ -34,6 +48,12 @@ func (v *VFS) LoadFromDisk(path string) error {
 		IsDir:   true,
 		ModTime: time.Now(),
 	}
+	v.lastInode = 0
+	names := newIDNames()
+	if info, err := os.Stat(absPath); err == nil {
+		v.Root.setHostMetadata(info, names)
+	}
+	usage := &totals{root: v.Root, nodes: 1, owners: map[string]int64{}}
 	// Рекурсивный обход всех файлов и папок
 	err = filepath.Walk(absPath, func(filePath string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -47,9 +67,17 @@ func (v *VFS) LoadFromDisk(path string) error {
 			return err
 		}
 		node := &VFSNode{
-			Name:    info.Name(),
-			IsDir:   info.IsDir(),
-			ModTime: info.ModTime(),
+			Name:  info.Name(),
+			IsDir: info.IsDir(),
+		}
+		node.setHostMetadata(info, names)
+		// Ограничения проверяются до чтения файла, чтобы не загружать в память лишнее
+		var size int64
+		if !info.IsDir() {
+			size = info.Size()
+		}
+		if err := v.checkLoad(filePath, filepath.ToSlash(relPath), node, size, usage); err != nil {
+			return err
 		}
 		if !info.IsDir() {
 			content, err := os.ReadFile(filePath)
@@ -61,11 +89,10 @@ func (v *VFS) LoadFromDisk(path string) error {
 		v.addNode(relPath, node)
 		return nil
 	})
+	v.mu.Lock()
+	v.totals = *usage
+	v.mu.Unlock()
 	v.IsLoaded = true
-	if err == nil {
-		fmt.Printf("VFS loaded from: %v\n", path)
-		v.PrintMOTD()
-	}
 	return err
 }
 
@@ -126,10 +153,10 @@ func (v *VFS) saveNode(node *VFSNode, basePath string) error {
 			}
 		}
 	} else {
-		if err := os.WriteFile(nodePath, []byte(node.Content), 0644); err != nil {
+		if err := os.WriteFile(nodePath, []byte(node.Content), node.FileMode().Perm()); err != nil {
 			return err
 		}
-		os.Chtimes(nodePath, time.Now(), node.ModTime)
+		os.Chtimes(nodePath, node.AccessTime(), node.ModTime)
 	}
 	return nil
 }
@@ -149,6 +176,10 @@ func (v *VFS) FindNode(path string) (*VFSNode, error) {
 			current = v.Root
 			continue
 		}
+		// Промежуточный элемент пути должен быть каталогом
+		if !current.IsDir {
+			return nil, &PathError{Op: "find", Path: path, Err: ErrNotDir}
+		}
 		found := false
 		for _, child := range current.Children {
 			if child.Name == part {
@@ -158,52 +189,48 @@ func (v *VFS) FindNode(path string) (*VFSNode, error) {
 			}
 		}
 		if !found {
-			return nil, fmt.Errorf("file or directory doesn`t found %s", part)
+			return nil, &PathError{Op: "find", Path: path, Err: ErrNotExist}
 		}
 	}
 	return current, nil
 }
 
-func (v *VFS) PrintMOTD() {
-	motdNode, err := v.FindNode("motd")
-	if err == nil && !motdNode.IsDir {
-		fmt.Printf("%s\n", motdNode.Content)
-	}
-}
-
 // Перемещает/переименовывает узел
 func (v *VFS) MoveNode(sourcePath, destPath string) error {
 	// Находим исходный узел
 	sourceNode, err := v.FindNode(sourcePath)
 	if err != nil {
-		return err
+		return withOp("rename", err)
 	}
 
 	// Находим родительский каталог источника
 	sourceParentPath := getParentPath(sourcePath)
 	sourceParent, err := v.FindNode(sourceParentPath)
 	if err != nil {
-		return err
+		return withOp("rename", err)
 	}
 
 	// Находим родительский каталог назначения
 	destParentPath := getParentPath(destPath)
 	destParent, err := v.FindNode(destParentPath)
 	if err != nil {
-		return err
+		return withOp("rename", err)
 	}
 
 	if !destParent.IsDir {
-		return fmt.Errorf("destination parent is not a directory")
+		return &PathError{Op: "rename", Path: destPath, Err: ErrNotDir}
 	}
 
 	// Получаем новое имя из целевого пути
 	destName := getNameFromPath(destPath)
+	if err := v.checkPath("rename", destPath, destName, pathDepth(destPath)+sourceNode.height()); err != nil {
+		return err
+	}
 
 	// Проверяем, не существует ли уже узел с таким именем в целевой директории
 	for _, child := range destParent.Children {
 		if child.Name == destName {
-			return fmt.Errorf("file or directory already exists")
+			return &PathError{Op: "rename", Path: destPath, Err: ErrExist}
 		}
 	}
 
@@ -218,10 +245,40 @@ func (v *VFS) MoveNode(sourcePath, destPath string) error {
 	// Меняем имя узла и добавляем в нового родителя
 	sourceNode.Name = destName
 	sourceNode.ModTime = time.Now()
+	sourceNode.CTime = sourceNode.ModTime
 	destParent.Children = append(destParent.Children, sourceNode)
 
 	return nil
 }
+
+// Удаляет узел вместе с содержимым
+func (v *VFS) RemoveNode(path string) error {
+	node, err := v.FindNode(path)
+	if err != nil {
+		return withOp("remove", err)
+	}
+	if node == v.Root {
+		return &PathError{Op: "remove", Path: path, Err: ErrPermission}
+	}
+	parent, err := v.FindNode(getParentPath(path))
+	if err != nil {
+		return withOp("remove", err)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i, child := range parent.Children {
+		if child == node {
+			// Итоги берутся до изменения дерева: при первом обращении они считаются обходом
+			usage := v.usage()
+			parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+			parent.ModTime = time.Now()
+			v.walk(node, func(n *VFSNode) { usage.add(n, -1) })
+			return nil
+		}
+	}
+	return &PathError{Op: "remove", Path: path, Err: ErrNotExist}
+}
+
 func getParentPath(path string) string {
 	cleanPath := strings.Trim(path, "/")
 	parts := strings.Split(cleanPath, "/")
@@ -0,0 +1,302 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"text/template"
+	"time"
+)
+
+func testVFS() *VFS {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &VFS{Root: &VFSNode{Name: "root", IsDir: true, ModTime: modTime, Children: []*VFSNode{
+		{Name: "motd", Content: "Welcome\n", ModTime: modTime},
+		{Name: "home", IsDir: true, ModTime: modTime, Children: []*VFSNode{
+			{Name: "user", IsDir: true, ModTime: modTime, Children: []*VFSNode{
+				{Name: "notes.txt", Content: "b\na\n", Owner: "user", ModTime: modTime},
+				{Name: "hello.tmpl", Content: "Hello, {{.}}!", ModTime: modTime},
+			}},
+		}},
+		{Name: "empty", IsDir: true, ModTime: modTime},
+	}}, IsLoaded: true}
+}
+
+func TestFS(t *testing.T) {
+	fsys := testVFS()
+	if err := fstest.TestFS(fsys, "motd", "home/user/notes.txt", "home/user/hello.tmpl", "empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		paths = append(paths, p)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{".", "empty", "home", "home/user", "home/user/hello.tmpl", "home/user/notes.txt", "motd"}
+	if !slices.Equal(paths, expected) {
+		t.Errorf("expected walk %v, got %v", expected, paths)
+	}
+
+	info, err := fs.Stat(fsys, "home/user/notes.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 4 || info.Mode() != 0o644 || !info.ModTime().Equal(fsys.Root.ModTime) {
+		t.Errorf("unexpected file info: size %d, mode %v, modTime %v", info.Size(), info.Mode(), info.ModTime())
+	}
+	if node, ok := info.Sys().(*VFSNode); !ok || node.Owner != "user" {
+		t.Errorf("expected Sys() to return the VFS node, got %#v", info.Sys())
+	}
+
+	tmpl, err := template.ParseFS(fsys, "home/user/*.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Name() != "hello.tmpl" {
+		t.Errorf("expected template hello.tmpl, got %s", tmpl.Name())
+	}
+}
+
+func TestFSErrors(t *testing.T) {
+	fsys := testVFS()
+	cases := []struct {
+		err    error
+		target error
+	}{
+		{func() error { _, err := fsys.Open("missing"); return err }(), fs.ErrNotExist},
+		{func() error { _, err := fsys.Open("/motd"); return err }(), fs.ErrInvalid},
+		{func() error { _, err := fsys.ReadFile("home"); return err }(), ErrIsDir},
+		{func() error { _, err := fsys.ReadDir("motd"); return err }(), ErrNotDir},
+		{func() error { _, err := fsys.Stat("motd/x"); return err }(), ErrNotDir},
+	}
+	for i, tc := range cases {
+		if !errors.Is(tc.err, tc.target) {
+			t.Errorf("case %d: expected errors.Is(%v, %v)", i, tc.err, tc.target)
+		}
+	}
+}
+
+func TestFileHandles(t *testing.T) {
+	fsys := testVFS()
+	before := fsys.Root.ModTime
+
+	f, err := fsys.Create("/home/user/new.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("hello world\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(6, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("there")
+	f.Seek(0, io.SeekStart)
+	data, err := io.ReadAll(f)
+	if err != nil || string(data) != "hello there\n" {
+		t.Errorf("expected %q, got %q (%v)", "hello there\n", data, err)
+	}
+	f.Close()
+	if _, err := f.Write([]byte("x")); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("expected fs.ErrClosed after Close, got %v", err)
+	}
+	node, _ := fsys.FindNode("/home/user/new.txt")
+	if !node.ModTime.After(before) {
+		t.Errorf("expected ModTime to be updated, got %v", node.ModTime)
+	}
+
+	// Запись в режиме O_APPEND всегда идет в конец файла
+	f, _ = fsys.OpenFile("/motd", os.O_WRONLY|os.O_APPEND)
+	f.Seek(0, io.SeekStart)
+	f.WriteString("Bye\n")
+	f.Close()
+	if content, _ := fsys.ReadFile("motd"); string(content) != "Welcome\nBye\n" {
+		t.Errorf("expected appended content, got %q", content)
+	}
+
+	f, _ = fsys.OpenFile("/motd", os.O_WRONLY|os.O_TRUNC)
+	f.Close()
+	if content, _ := fsys.ReadFile("motd"); len(content) != 0 {
+		t.Errorf("expected truncated file, got %q", content)
+	}
+
+	errorCases := []struct {
+		flag   int
+		name   string
+		target error
+	}{
+		{os.O_RDONLY, "/missing", ErrNotExist},
+		{os.O_WRONLY | os.O_CREATE | os.O_EXCL, "/motd", ErrExist},
+		{os.O_WRONLY | os.O_CREATE, "/nodir/a.txt", ErrNotExist},
+		{os.O_WRONLY | os.O_CREATE, "/motd/a.txt", ErrNotDir},
+		{os.O_WRONLY, "/home", ErrIsDir},
+	}
+	for _, tc := range errorCases {
+		if _, err := fsys.OpenFile(tc.name, tc.flag); !errors.Is(err, tc.target) {
+			t.Errorf("OpenFile(%q, %#x): expected %v, got %v", tc.name, tc.flag, tc.target, err)
+		}
+	}
+	f, _ = fsys.OpenFile("/home/user/notes.txt", os.O_RDONLY)
+	if _, err := f.Write([]byte("x")); !errors.Is(err, ErrPermission) {
+		t.Errorf("expected ErrPermission writing a read-only handle, got %v", err)
+	}
+
+	// Размер для io.SeekEnd читается согласованно с записью через другой дескриптор (go test -race)
+	w, _ := fsys.OpenFile("/home/user/notes.txt", os.O_WRONLY|os.O_APPEND)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			w.WriteString("x")
+		}
+	}()
+	var size int64
+	for range 100 {
+		size, _ = f.Seek(0, io.SeekEnd)
+	}
+	<-done
+	if end, _ := f.Seek(0, io.SeekEnd); end != 104 || size > end {
+		t.Errorf("expected size 104 after appends, got %d (intermediate %d)", end, size)
+	}
+}
+
+func TestLoadMetadata(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(file, []byte("data"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	atime := time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)
+	mtime := time.Date(2022, 2, 3, 4, 5, 6, 0, time.UTC)
+	if err := os.Chtimes(file, atime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	v := &VFS{}
+	if err := v.LoadFromDisk(dir); err != nil {
+		t.Fatal(err)
+	}
+	st, err := v.StatPath("/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Size != 4 || st.Mode != 0o640 || !st.MTime.Equal(mtime) {
+		t.Errorf("unexpected metadata: size %d, mode %v, mtime %v", st.Size, st.Mode, st.MTime)
+	}
+	if runtime.GOOS != "windows" && runtime.GOOS != "plan9" && runtime.GOOS != "js" {
+		if !st.ATime.Equal(atime) || st.Owner == "" {
+			t.Errorf("expected host atime %v and owner, got %v and %q", atime, st.ATime, st.Owner)
+		}
+	}
+	if root, _ := v.StatPath("/"); !root.IsDir || root.Inode == st.Inode {
+		t.Errorf("unexpected root metadata %+v", root)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	fsys := testVFS()
+	fsys.Limits = Limits{
+		MaxBytes:      40,
+		MaxNodes:      9,
+		MaxFileSize:   16,
+		MaxDepth:      3,
+		MaxNameLength: 10,
+		OwnerQuotas:   map[string]int64{"user": 8},
+	}
+	// 7 узлов, 25 байт: notes.txt пользователя user занимает 4 байта
+	f, err := fsys.Create("/empty/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		err    error
+		target error
+	}{
+		{"file size", func() error { _, err := f.Write(make([]byte, 17)); return err }(), ErrFileTooLarge},
+		{"truncate", f.Truncate(17), ErrFileTooLarge},
+		{"total bytes", func() error { _, err := f.Write(make([]byte, 16)); return err }(), ErrNoSpace},
+		{"node count", func() error { _, err := fsys.Create("/empty/b.txt"); _, err = fsys.Create("/empty/c.txt"); return err }(), ErrNoSpace},
+		{"name length", func() error { _, err := fsys.Create("/a-very-long-name"); return err }(), ErrNameTooLong},
+		{"depth", fsys.MoveNode("/home", "/empty/home"), ErrNameTooLong},
+		{"owner quota", func() error {
+			f, _ := fsys.OpenFile("/home/user/notes.txt", os.O_WRONLY|os.O_APPEND)
+			_, err := f.Write([]byte("12345"))
+			return err
+		}(), ErrQuota},
+		{"chown quota", fsys.Chown("/home/user/hello.tmpl", "user"), ErrQuota},
+	}
+	for _, tc := range cases {
+		if !errors.Is(tc.err, tc.target) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.target, tc.err)
+		}
+	}
+	if content, _ := fsys.ReadFile("empty/a.txt"); len(content) != 0 {
+		t.Errorf("expected failed writes to leave the file unchanged, got %q", content)
+	}
+	if _, err := f.Write(make([]byte, 15)); err != nil {
+		t.Errorf("expected a write within limits to succeed, got %v", err)
+	}
+	if owners := fsys.OwnerUsage(); owners["user"] != (OwnerUsage{Bytes: 4, Files: 1}) {
+		t.Errorf("unexpected owner usage %+v", owners)
+	}
+	// Новые файлы принадлежат пользователю VFS, а без него - владельцу каталога
+	fsys.Limits.MaxNodes, fsys.Limits.MaxBytes = 0, 0
+	fsys.Root.Children[1].Children[0].Owner = "user"
+	g, err := fsys.Create("/home/user/new.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Write([]byte("12345")); !errors.Is(err, ErrQuota) {
+		t.Errorf("expected ErrQuota for a new file in the owner's directory, got %v", err)
+	}
+	fsys.User = "user"
+	if g, err = fsys.Create("/empty/new.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := fsys.StatPath("/empty/new.txt"); st.Owner != "user" {
+		t.Errorf("expected the VFS user to own a new file, got %q", st.Owner)
+	}
+	if _, err := g.Write([]byte("12345")); !errors.Is(err, ErrQuota) {
+		t.Errorf("expected ErrQuota for a new file of the VFS user, got %v", err)
+	}
+	// Итоги, которые поддерживаются при изменениях, совпадают с подсчетом обходом дерева
+	g.Write([]byte("123"))
+	f.Truncate(3)
+	fsys.RemoveNode("/empty/a.txt")
+	fsys.Chown("/home/user/notes.txt", "bob")
+	fsys.MoveNode("/home/user", "/user")
+	usage, owners := fsys.Usage(), fsys.OwnerUsage()
+	if fsys.totals.nodes != usage.Nodes || fsys.totals.bytes != usage.Bytes ||
+		fsys.totals.owners["user"] != owners["user"].Bytes || fsys.totals.owners["bob"] != owners["bob"].Bytes {
+		t.Errorf("running totals %+v do not match usage %+v and owners %+v", fsys.totals, usage, owners)
+	}
+	// Узлы, добавленные напрямую, учитываются после Recount
+	fsys.Limits.MaxNodes = usage.Nodes + 1
+	fsys.Root.Children = append(fsys.Root.Children, &VFSNode{Name: "direct", IsDir: true})
+	fsys.Recount()
+	if _, err := fsys.Create("/extra"); !errors.Is(err, ErrNoSpace) {
+		t.Errorf("expected ErrNoSpace after Recount, got %v", err)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "small"), []byte("1234"), 0o644)
+	os.WriteFile(filepath.Join(dir, "large"), make([]byte, 100), 0o644)
+	v := &VFS{Limits: Limits{MaxBytes: 50}}
+	if err := v.LoadFromDisk(dir); !errors.Is(err, ErrNoSpace) {
+		t.Errorf("expected ErrNoSpace on load, got %v", err)
+	}
+	v = &VFS{Limits: Limits{MaxFileSize: 50}}
+	if err := v.LoadFromDisk(dir); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("expected ErrFileTooLarge on load, got %v", err)
+	}
+}